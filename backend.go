@@ -0,0 +1,164 @@
+package gowatcher
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A Backend notifies the gowatcher about the paths that may have changed, so that they are
+// polled right away instead of on the next polling cycle. The events are still generated by
+// comparing the file trees, so they are the same whether a backend is used or not.
+type Backend interface {
+	// Add starts watching a file or directory, it returns an error if the
+	// path can't be watched, in which case its tree falls back to polling.
+	Add(path string) error
+	// Remove stops watching a file or directory.
+	Remove(path string) error
+	// Changes returns the channel on which the paths that may have changed are sent.
+	// An empty path means that changes were lost and every path must be polled.
+	Changes() <-chan string
+	// Errors returns the channel on which an error is sent when the backend stopped
+	// working, the gowatcher then closes it and falls back to polling.
+	Errors() <-chan error
+	// Close stops the backend.
+	Close() error
+}
+
+// SetBackend makes the gowatcher use a Backend to notice changes as soon as they happen.
+// The paths that the backend fully watches are still polled every reconcile to catch any
+// missed change, the others are polled as usual.
+// Notice: This function should be called before Start.
+func (w *GoWatcher) SetBackend(b Backend, reconcile time.Duration) error {
+	if reconcile < time.Nanosecond {
+		return ErrDurationTooShort
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.backend != nil {
+		w.backend.Close()
+	}
+	w.backend, w.reconcile = b, reconcile
+	for _, t := range w.fileTrees {
+		w.watchTree(t)
+	}
+	return nil
+}
+
+// dropBackend closes the backend and falls back to polling every file tree.
+func (w *GoWatcher) dropBackend() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.backend == nil {
+		return
+	}
+	w.backend.Close()
+	w.backend = nil
+	for _, t := range w.fileTrees {
+		t.covered = false
+	}
+}
+
+// watchTree adds every polled node of the tree to the backend.
+func (w *GoWatcher) watchTree(t *fileTree) {
+	t.covered = w.backend != nil && t.root != nil && w.watchNode(t.root)
+}
+
+func (w *GoWatcher) unwatchTree(t *fileTree) {
+	if w.backend == nil || t.root == nil {
+		return
+	}
+	for path, node := range t.root.RetrieveAllNodes() {
+		if node.Info.IsDir() || path == t.path {
+			w.backend.Remove(path)
+		}
+	}
+}
+
+// watchNode adds the node and every directory below it whose children are polled
+// to the backend, it returns false if the backend failed to watch one of them.
+func (w *GoWatcher) watchNode(node *FileNode) bool {
+	if node.ignored {
+		return true
+	}
+	if err := w.backend.Add(node.Path); err != nil {
+		return false
+	}
+	if !node.Info.IsDir() || node.depth == 0 {
+		return true
+	}
+	for _, child := range node.Children {
+		if child != nil && child.Info.IsDir() && !w.watchNode(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// watchNewDirs adds the directories created or moved during a cycle to the backend.
+func (w *GoWatcher) watchNewDirs(dirs []string) {
+	if w.backend == nil {
+		return
+	}
+	for _, path := range dirs {
+		t, node, _ := w.findNode(path)
+		if node != nil && t.covered {
+			t.covered = w.watchNode(node)
+		}
+	}
+}
+
+// findNode returns the node of a path, its parent and the file tree it belongs to.
+// If the path is inside several file trees, the deepest one is used.
+func (w *GoWatcher) findNode(path string) (t *fileTree, node, parent *FileNode) {
+	t = w.treeOf(path)
+	if t == nil || t.root == nil {
+		return nil, nil, nil
+	}
+	node = t.root
+	rel := strings.TrimPrefix(path[len(t.path):], string(filepath.Separator))
+	if rel == "" {
+		return t, node, nil
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		parent, node = node, node.Children[name]
+		if node == nil {
+			return nil, nil, nil
+		}
+	}
+	return t, node, parent
+}
+
+// treeOf returns the file tree a path belongs to, the deepest one if the path is inside several file trees.
+func (w *GoWatcher) treeOf(path string) (t *fileTree) {
+	for root, tree := range w.fileTrees {
+		if path != root && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			continue
+		}
+		if t == nil || len(root) > len(t.path) {
+			t = tree
+		}
+	}
+	return t
+}
+
+// drainChanges returns the first changed path and every other one that's already pending,
+// or nil if any of them is empty.
+func drainChanges(changes <-chan string, path string) []string {
+	seen := map[string]bool{path: true}
+	paths := []string{path}
+	for {
+		if path == "" {
+			return nil
+		}
+		select {
+		case path = <-changes:
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		default:
+			return paths
+		}
+	}
+}
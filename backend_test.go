@@ -0,0 +1,120 @@
+package gowatcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeBackend is a Backend whose changes are sent by the tests.
+type fakeBackend struct {
+	changes chan string
+	errors  chan error
+	failAdd bool
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{changes: make(chan string), errors: make(chan error)}
+}
+
+func (b *fakeBackend) Add(path string) error {
+	if b.failAdd {
+		return errors.New("error: watch limit reached")
+	}
+	return nil
+}
+func (b *fakeBackend) Remove(path string) error { return nil }
+func (b *fakeBackend) Changes() <-chan string   { return b.changes }
+func (b *fakeBackend) Errors() <-chan error     { return b.errors }
+func (b *fakeBackend) Close() error             { return nil }
+
+// startBackendTest starts a gowatcher on testDir that only polls once an hour.
+func startBackendTest(t *testing.T, testDir string, b Backend) *GoWatcher {
+	w := New()
+	w.FilterOps(Create)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetBackend(b, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		if err := w.Start(time.Hour); err != nil {
+			t.Error(err)
+		}
+	}()
+	w.Wait()
+	// Let the first cycle, which polls every path, run.
+	time.Sleep(time.Millisecond * 50)
+	return w
+}
+
+func expectCreate(t *testing.T, w *GoWatcher, path string) {
+	select {
+	case event := <-w.Event:
+		if event.Path != path {
+			t.Errorf("expected a create event for %s, got %s", path, event)
+		}
+	case <-time.After(time.Millisecond * 250):
+		t.Fatalf("received no event for %s", path)
+	}
+}
+
+func TestBackendChanges(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	b := newFakeBackend()
+	w := startBackendTest(t, testDir, b)
+	defer w.Close()
+
+	dirTwo := filepath.Join(testDir, "testDirTwo")
+	newFile := filepath.Join(dirTwo, "newfile.txt")
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	b.changes <- dirTwo
+	expectCreate(t, w, newFile)
+}
+
+func TestBackendFallback(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	b := newFakeBackend()
+	w := startBackendTest(t, testDir, b)
+	defer w.Close()
+
+	// Once the backend fails, every path is polled again.
+	newFile := filepath.Join(testDir, "newfile.txt")
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	b.errors <- errors.New("error: backend failed")
+	expectCreate(t, w, newFile)
+}
+
+func TestBackendAddFailure(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	b := newFakeBackend()
+	b.failAdd = true
+	if err := w.SetBackend(b, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// A path the backend can't watch is polled on the interval passed to Start.
+	if w.fileTrees[testDir].covered {
+		t.Errorf("expected %s to not be covered by the backend", testDir)
+	}
+	if d := w.treeInterval(w.fileTrees[testDir]); d != 0 {
+		t.Errorf("expected %s to be polled on the default interval, got %s", testDir, d)
+	}
+}
@@ -0,0 +1,36 @@
+package gowatcher
+
+import (
+	"context"
+	"time"
+)
+
+// Batch holds the events found by one polling cycle.
+type Batch struct {
+	Seq      uint64        // Sequence number of the batch, starting at 1.
+	Start    time.Time     // When the cycle started.
+	Duration time.Duration // How long the cycle took to scan the file trees.
+	Events   []Event
+}
+
+// SetBatchMode makes the gowatcher send the events of every polling cycle on the Batches channel
+// as a single Batch, instead of sending them one by one on the Event channel. A batch is sent after
+// every cycle, even if it found no events. When debouncing, the events of the paths that became quiet
+// are sent as their own batch, whose Duration is 0.
+// Notice: This function should be called before Start.
+func (w *GoWatcher) SetBatchMode(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batchMode = enabled
+}
+
+// sendBatch sends a batch on the Batches channel, it returns false if the context was cancelled first.
+func (w *GoWatcher) sendBatch(ctx context.Context, b Batch) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.Batches <- Batch{Seq: w.batchSeq + 1, Start: b.Start, Duration: b.Duration, Events: b.Events}:
+		w.batchSeq++
+		return true
+	}
+}
@@ -0,0 +1,77 @@
+package gowatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBatchMode(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetBatchMode(true)
+	w.FilterOps(Create)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, 10*time.Millisecond)
+	}()
+	w.Wait()
+
+	newFiles := map[string]bool{
+		filepath.Join(testDir, "new_1.txt"): true,
+		filepath.Join(testDir, "new_2.txt"): true,
+	}
+
+	var seq uint64
+	var created bool
+	timeout := time.After(time.Second)
+	for len(newFiles) > 0 {
+		select {
+		case b := <-w.Batches:
+			if b.Seq != seq+1 {
+				t.Errorf("expected batch %d, got %d", seq+1, b.Seq)
+			}
+			seq = b.Seq
+			if b.Start.IsZero() || b.Duration < 0 {
+				t.Errorf("expected the start and duration of the cycle, got %v and %v", b.Start, b.Duration)
+			}
+			if !created {
+				// Both files are created before the next cycle, so they're in the same batch.
+				for path := range newFiles {
+					if err := ioutil.WriteFile(path, []byte{}, 0755); err != nil {
+						t.Fatal(err)
+					}
+				}
+				created = true
+				continue
+			}
+			if len(b.Events) == 0 {
+				continue
+			}
+			if len(b.Events) != len(newFiles) {
+				t.Fatalf("expected %d events in the batch, got %v", len(newFiles), b.Events)
+			}
+			for _, event := range b.Events {
+				delete(newFiles, event.Path)
+			}
+		case event := <-w.Event:
+			t.Fatalf("unexpected event %v in batch mode", event)
+		case <-timeout:
+			t.Fatal("received no batch with the created files")
+		}
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected error to be %s, got %v", context.Canceled, err)
+	}
+}
@@ -23,6 +23,10 @@ func main() {
 	stdinPipe := flag.Bool("pipe", false, "pipe event's info to command's stdin")
 	keepalive := flag.Bool("keepalive", false, "keep alive when a cmd returns code != 0")
 	ignore := flag.String("ignore", "", "comma separated list of paths to ignore")
+	gitignore := flag.Bool("gitignore", false, "ignore the files ignored by the .gitignore files")
+	inotify := flag.Bool("inotify", false, "use inotify to notice changes right away (Linux only)")
+	reconcile := flag.String("reconcile", "1m", "poll interval of the paths watched by inotify")
+	debounce := flag.String("debounce", "0s", "only run the command once the events stopped for this duration")
 
 	flag.Parse()
 
@@ -51,6 +55,7 @@ func main() {
 	// Create a new Watcher with the specified options.
 	w := gowatcher.New()
 	w.IgnoreHiddenFiles(!*dotfiles)
+	w.LoadGitignore(*gitignore)
 
 	// Get any of the paths to ignore.
 	ignoredPaths := strings.Split(*ignore, ",")
@@ -96,7 +101,7 @@ func main() {
 					}
 				}
 			case err := <-w.Error:
-				if err == gowatcher.ErrWatchedFileDeleted {
+				if _, ok := err.(*gowatcher.RootError); ok || err == gowatcher.ErrWatchedFileDeleted {
 					fmt.Println(err)
 					continue
 				}
@@ -137,6 +142,28 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	// Use inotify if requested, the paths it can't watch are still polled.
+	if *inotify {
+		parsedReconcile, err := time.ParseDuration(*reconcile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		backend, err := gowatcher.NewInotifyBackend()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := w.SetBackend(backend, parsedReconcile); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// Merge the events of a path until it's quiet, so that the command runs once per burst.
+	parsedDebounce, err := time.ParseDuration(*debounce)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	w.SetDebounce(parsedDebounce)
+
 	closed := make(chan struct{})
 
 	c := make(chan os.Signal)
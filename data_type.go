@@ -3,6 +3,7 @@ package gowatcher
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -18,17 +19,27 @@ const (
 	Write
 	Remove
 	Chmod
-	//Rename
-	//Move
+	Rename
+	Move
+	Replace
+	Chown
+	Xattr
+	Exists
+	Ready
 )
 
 var ops = map[Op]string{
-	Create: "CREATE",
-	Write:  "WRITE",
-	Remove: "REMOVE",
-	Chmod:  "CHMOD",
-	//Rename: "RENAME",
-	//Move:   "MOVE",
+	Create:  "CREATE",
+	Write:   "WRITE",
+	Remove:  "REMOVE",
+	Chmod:   "CHMOD",
+	Rename:  "RENAME",
+	Move:    "MOVE",
+	Replace: "REPLACE",
+	Chown:   "CHOWN",
+	Xattr:   "XATTR",
+	Exists:  "EXISTS",
+	Ready:   "READY",
 }
 
 // String prints the string version of the Op consts
@@ -42,10 +53,44 @@ func (e Op) String() string {
 // An Event describes an event that is received when files or directory
 // changes occur. It includes the os.FileInfo of the changed file or
 // directory and the type of event that's occurred and the full path of the file.
+// For Rename and Move events, OldPath holds the path the file was moved from.
+// Digest holds the digest of the file's content when content hashing is enabled.
+// For Chown and Xattr events, the old and new owner or extended attributes are set.
+// Exists and Ready events are only sent with SetInitialEvents.
+// For the changes of a file, OldInfo holds its os.FileInfo before the change.
 type Event struct {
 	Op
-	Path string
+	Path    string
+	OldPath string
 	os.FileInfo
+	OldInfo os.FileInfo
+	Digest  []byte
+
+	OldOwner  Owner
+	NewOwner  Owner
+	OldXattrs map[string][]byte
+	NewXattrs map[string][]byte
+
+	Root    string    // The watched root the path belongs to
+	RelPath string    // Path relative to the root, "." for the root itself
+	Time    time.Time // When the event was detected
+	Seq     uint64    // Increases with every event found by the gowatcher, 0 for triggered events
+
+	filters *filterSet // the filters of the root, nil for the gowatcher's ones
+}
+
+// setRoot sets the watched root of the event and its path relative to it.
+func (e *Event) setRoot(root string) {
+	e.Root = root
+	if rel, err := filepath.Rel(root, e.Path); err == nil {
+		e.RelPath = rel
+	}
+}
+
+// Owner is the user and group that own a file.
+type Owner struct {
+	UID int
+	GID int
 }
 
 // String returns a string depending on what type of event occurred and the
@@ -59,6 +104,9 @@ func (e Event) String() string {
 	if e.IsDir() {
 		pathType = "DIRECTORY"
 	}
+	if e.Op == Rename || e.Op == Move {
+		return fmt.Sprintf("%s %q %s [%s -> %s]", pathType, e.Name(), e.Op, e.OldPath, e.Path)
+	}
 	return fmt.Sprintf("%s %q %s [%s]", pathType, e.Name(), e.Op, e.Path)
 }
 
@@ -66,23 +114,62 @@ func (e Event) String() string {
 Using a Trie tree data structure to improve the refresh and poll event performance
 */
 type FileNode struct {
-	Path      string      // Full path
-	Info      os.FileInfo // File info
-	ignored   bool        // Whether this FileNode ignored. If ignored, gowatcher won't try to find its children
-	recursive bool        // Whether this FileNode should be recursively traversed
-	mu        *sync.RWMutex
-	Children  map[string]*FileNode // Children nodes, use filename as key
+	Path     string            // Full path
+	Info     os.FileInfo       // File info, of the target if it's a followed symlink
+	Link     string            // Target of a followed symlink
+	ignored  bool              // Whether this FileNode ignored. If ignored, gowatcher won't try to find its children
+	depth    int               // Levels of descendants watched below this FileNode, no limit if negative
+	Digest   []byte            // Digest of the file's content, nil if content hashing is disabled
+	Owner    Owner             // Owner of the file, only available on Linux
+	Xattrs   map[string][]byte // Extended attributes, nil unless ChangeXattr is in the change policy
+	mu       *sync.RWMutex
+	rules    *ignoreRules         // Ignore rules applying to the children of a directory
+	parent   *FileNode            // Parent node, nil for the root of a tree
+	Children map[string]*FileNode // Children nodes, use filename as key
 }
 
-func newNode(path string, info os.FileInfo, recursive bool, ignored bool) *FileNode {
+// fileTree is a watched path added to the gowatcher and its polling schedule.
+type fileTree struct {
+	path     string
+	root     *FileNode     // nil while the path doesn't exist
+	maxDepth int           // levels of descendants watched below the root, no limit if negative
+	interval time.Duration // the tree's own polling interval, 0 for the interval passed to Start
+	priority int           // trees with a higher priority are polled first
+	next     time.Time     // when a tree with its own interval is due to be polled again
+	covered  bool          // whether the backend watches every directory of the tree
+	ancestor string        // nearest existing ancestor of a missing root, checked until the root appears
+	filters  *filterSet    // the tree's own filters, nil for the gowatcher's ones
+	opts     *Options      // the options the filters were compiled from
+}
+
+// event sets the root and the filters of the file tree on an event found in the tree, and the
+// time it was found. The Create and Remove events keep this time while they're held by the cycle.
+func (t *fileTree) event(e Event) Event {
+	e.setRoot(t.path)
+	e.filters = t.filters
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return e
+}
+
+func newNode(path string, info os.FileInfo, depth int, ignored bool) *FileNode {
 	return &FileNode{
-		Path:      path,
-		Info:      info,
-		mu:        new(sync.RWMutex),
-		recursive: recursive,
-		Children:  make(map[string]*FileNode),
-		ignored:   ignored || info.Mode()&os.ModeSymlink != 0,
+		Path:     path,
+		Info:     info,
+		mu:       new(sync.RWMutex),
+		depth:    depth,
+		Children: make(map[string]*FileNode),
+		ignored:  ignored || info.Mode()&os.ModeSymlink != 0,
+	}
+}
+
+// childDepth returns the depth of the children of a node.
+func (node *FileNode) childDepth() int {
+	if node.depth < 0 {
+		return node.depth
 	}
+	return node.depth - 1
 }
 
 func (node *FileNode) String() string {
@@ -0,0 +1,198 @@
+package gowatcher
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SetDebounce makes the gowatcher hold the events of a path until no event was found for it
+// during the quiet duration, and merge them: a Create followed by changes is a single Create,
+// a Create followed by a Remove is dropped, and a Write and a Chmod are a single Write.
+// Debouncing is disabled if quiet is less than 1ns, which is the default.
+// Notice: This function should be called before Start.
+func (w *GoWatcher) SetDebounce(quiet time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if quiet < time.Nanosecond {
+		w.debounce = nil
+		return
+	}
+	w.debounce = newDebouncer(quiet)
+}
+
+// debouncer holds the events of the paths that haven't been quiet long enough.
+// It's only used by the goroutine running the polling cycles.
+type debouncer struct {
+	quiet   time.Duration
+	seq     int // orders the held events of different paths by their first event
+	pending map[string]*debounceEntry
+}
+
+type debounceEntry struct {
+	path   string
+	seq    int
+	last   time.Time // when the last event of the path was found
+	events []Event
+}
+
+func newDebouncer(quiet time.Duration) *debouncer {
+	return &debouncer{quiet: quiet, pending: make(map[string]*debounceEntry)}
+}
+
+// add holds an event, merged with the ones held for the same path.
+func (d *debouncer) add(e Event, now time.Time) {
+	var events []Event
+	seq := d.seq
+	d.seq++
+	// The events held for the old path of a renamed or moved node now belong to the new path.
+	if e.Op == Rename || e.Op == Move {
+		if old, found := d.pending[e.OldPath]; found {
+			delete(d.pending, e.OldPath)
+			seq = old.seq
+			events = old.events
+		}
+	}
+
+	entry, found := d.pending[e.Path]
+	if !found {
+		entry = &debounceEntry{path: e.Path, seq: seq}
+		d.pending[e.Path] = entry
+	} else if seq < entry.seq {
+		entry.seq = seq
+	}
+	entry.last = now
+	if len(events) > 0 && events[0].Op == Create {
+		// A node that was created and moved while held is only created at its new path.
+		e.Op, e.OldPath = Create, ""
+		events = nil
+	}
+	entry.events = append(entry.events, events...)
+	entry.events = mergeEvent(entry.events, e)
+	if len(entry.events) == 0 {
+		delete(d.pending, e.Path)
+	}
+}
+
+// mergeEvent merges an event into the events held for its path.
+func mergeEvent(events []Event, e Event) []Event {
+	if len(events) == 0 {
+		return []Event{e}
+	}
+	last := &events[len(events)-1]
+	switch e.Op {
+	case Remove:
+		if events[0].Op == Create {
+			return nil
+		}
+		// Only the removal of the path known before the first held event matters.
+		for _, held := range events {
+			if held.Op == Rename || held.Op == Move {
+				e.Path = held.OldPath
+				break
+			}
+		}
+		return []Event{e}
+	case Create, Rename, Move:
+		return append(events, e)
+	}
+
+	// e is a change of the node, merge it with the last held event if possible.
+	switch {
+	case last.Op == Create:
+		last.FileInfo, last.Digest = e.FileInfo, e.Digest
+	case last.Op == e.Op:
+		last.FileInfo, last.Digest = e.FileInfo, e.Digest
+		last.NewOwner, last.NewXattrs = e.NewOwner, e.NewXattrs
+	case (last.Op == Write || last.Op == Chmod) && (e.Op == Write || e.Op == Chmod):
+		last.Op = Write
+		last.FileInfo, last.Digest = e.FileInfo, e.Digest
+	default:
+		return append(events, e)
+	}
+	return events
+}
+
+// ready removes and returns the entries of the paths that have been quiet long enough,
+// in the order of their first event.
+func (d *debouncer) ready(now time.Time) []*debounceEntry {
+	var entries []*debounceEntry
+	for path, entry := range d.pending {
+		if now.Sub(entry.last) >= d.quiet {
+			entries = append(entries, entry)
+			delete(d.pending, path)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	return entries
+}
+
+// restore holds the entries again, so that they're sent after the next quiet period.
+func (d *debouncer) restore(entries []*debounceEntry) {
+	for _, entry := range entries {
+		if held, found := d.pending[entry.path]; found {
+			held.seq = entry.seq
+			held.events = append(entry.events, held.events...)
+			continue
+		}
+		d.pending[entry.path] = entry
+	}
+}
+
+// next returns when the earliest held path will have been quiet long enough.
+func (d *debouncer) next() (next time.Time, ok bool) {
+	for _, entry := range d.pending {
+		if t := entry.last.Add(d.quiet); !ok || t.Before(next) {
+			next, ok = t, true
+		}
+	}
+	return next, ok
+}
+
+// quietAfter returns a channel that receives once the earliest held path has been quiet
+// long enough, or nil if there's nothing held.
+func (w *GoWatcher) quietAfter() <-chan time.Time {
+	if w.debounce == nil {
+		return nil
+	}
+	next, ok := w.debounce.next()
+	if !ok {
+		return nil
+	}
+	return time.After(time.Until(next))
+}
+
+// flushDebounced sends the held events of the paths that have been quiet long enough,
+// as a single batch in batch mode.
+// The events that couldn't be sent before the context was cancelled are held again.
+func (w *GoWatcher) flushDebounced(ctx context.Context) {
+	if w.debounce == nil {
+		return
+	}
+	now := time.Now()
+	entries := w.debounce.ready(now)
+	if w.batchMode {
+		if len(entries) == 0 {
+			return
+		}
+		var events []Event
+		for _, entry := range entries {
+			events = append(events, entry.events...)
+		}
+		if !w.sendBatch(ctx, Batch{Start: now, Events: events}) {
+			w.debounce.restore(entries)
+		}
+		return
+	}
+	for i, entry := range entries {
+		for j, event := range entry.events {
+			select {
+			case <-ctx.Done():
+				entry.events = entry.events[j:]
+				w.debounce.restore(entries[i:])
+				return
+			case w.Event <- event:
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gowatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebouncerMerge(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name     string
+		events   []Event
+		expected []Event
+	}{
+		{
+			"create and write",
+			[]Event{{Op: Create, Path: "a"}, {Op: Write, Path: "a"}, {Op: Chmod, Path: "a"}},
+			[]Event{{Op: Create, Path: "a"}},
+		},
+		{
+			"create and remove",
+			[]Event{{Op: Create, Path: "a"}, {Op: Write, Path: "a"}, {Op: Remove, Path: "a"}},
+			nil,
+		},
+		{
+			"write and chmod",
+			[]Event{{Op: Chmod, Path: "a"}, {Op: Write, Path: "a"}, {Op: Chmod, Path: "a"}},
+			[]Event{{Op: Write, Path: "a"}},
+		},
+		{
+			"write and remove",
+			[]Event{{Op: Write, Path: "a"}, {Op: Remove, Path: "a"}},
+			[]Event{{Op: Remove, Path: "a"}},
+		},
+		{
+			"create and rename",
+			[]Event{{Op: Create, Path: "a"}, {Op: Rename, Path: "b", OldPath: "a"}},
+			[]Event{{Op: Create, Path: "b"}},
+		},
+		{
+			"rename and remove",
+			[]Event{{Op: Write, Path: "a"}, {Op: Rename, Path: "b", OldPath: "a"}, {Op: Remove, Path: "b"}},
+			[]Event{{Op: Remove, Path: "a"}},
+		},
+		{
+			"different paths",
+			[]Event{{Op: Write, Path: "b"}, {Op: Create, Path: "a"}, {Op: Write, Path: "b"}},
+			[]Event{{Op: Write, Path: "b"}, {Op: Create, Path: "a"}},
+		},
+	}
+
+	for _, c := range cases {
+		d := newDebouncer(time.Second)
+		for _, e := range c.events {
+			d.add(e, now)
+		}
+		if entries := d.ready(now); len(entries) != 0 {
+			t.Errorf("%s: expected no events before the quiet period, got %d paths", c.name, len(entries))
+		}
+		var events []Event
+		for _, entry := range d.ready(now.Add(time.Second)) {
+			events = append(events, entry.events...)
+		}
+		if len(events) != len(c.expected) {
+			t.Errorf("%s: expected events %v, got %v", c.name, c.expected, events)
+			continue
+		}
+		for i, e := range events {
+			if e.Op != c.expected[i].Op || e.Path != c.expected[i].Path || e.OldPath != c.expected[i].OldPath {
+				t.Errorf("%s: expected events %v, got %v", c.name, c.expected, events)
+				break
+			}
+		}
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetDebounce(100 * time.Millisecond)
+	w.FilterOps(Create, Write)
+	w.FilterName(`^new\.txt$`)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, 10*time.Millisecond)
+	}()
+	w.Wait()
+
+	// Keep writing the new file for several cycles.
+	newFile := filepath.Join(testDir, "new.txt")
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := ioutil.WriteFile(newFile, []byte("content"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := chtimes(newFile, start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case event := <-w.Event:
+		if event.Op != Create || event.Path != newFile {
+			t.Errorf("expected a create event for %s, got %v", newFile, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("received no event")
+	}
+	select {
+	case event := <-w.Event:
+		t.Errorf("expected a single event, got %v", event)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected error to be %s, got %v", context.Canceled, err)
+	}
+}
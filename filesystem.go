@@ -0,0 +1,89 @@
+package gowatcher
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem is the filesystem watched by the gowatcher. Every path passed to
+// it is absolute, as returned by Abs, and uses the OS path separator.
+type FileSystem interface {
+	// Abs returns the absolute representation of a path.
+	Abs(path string) (string, error)
+	// Lstat returns the os.FileInfo of a path, without following symlinks.
+	Lstat(path string) (os.FileInfo, error)
+	// ReadDir returns the os.FileInfo of the entries of a directory, sorted by name.
+	ReadDir(path string) ([]os.FileInfo, error)
+	// Open opens a file for reading its content.
+	Open(path string) (io.ReadCloser, error)
+	// IsHidden reports whether a path is a hidden file or directory.
+	IsHidden(path string) (bool, error)
+	// SameFile reports whether two os.FileInfo returned by the FileSystem describe the same file.
+	SameFile(fi1, fi2 os.FileInfo) bool
+}
+
+// XattrFileSystem is a FileSystem that provides the extended attributes of files.
+type XattrFileSystem interface {
+	FileSystem
+	// Xattrs returns the extended attributes of a path.
+	Xattrs(path string) (map[string][]byte, error)
+}
+
+// SymlinkFileSystem is a FileSystem that can resolve symlinks, which is required to follow them.
+type SymlinkFileSystem interface {
+	FileSystem
+	// Stat returns the os.FileInfo of a path, following symlinks.
+	Stat(path string) (os.FileInfo, error)
+	// Readlink returns the target of a symlink.
+	Readlink(path string) (string, error)
+}
+
+// OSFileSystem is the FileSystem of the operating system, it's used by default.
+type OSFileSystem struct{}
+
+func (OSFileSystem) Abs(path string) (string, error) {
+	return filepath.Abs(path)
+}
+
+func (OSFileSystem) Lstat(path string) (os.FileInfo, error) {
+	return os.Lstat(path)
+}
+
+func (OSFileSystem) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (OSFileSystem) Readlink(path string) (string, error) {
+	return os.Readlink(path)
+}
+
+func (OSFileSystem) ReadDir(path string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(path)
+}
+
+func (OSFileSystem) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func (OSFileSystem) IsHidden(path string) (bool, error) {
+	return isHiddenFile(path)
+}
+
+func (OSFileSystem) SameFile(fi1, fi2 os.FileInfo) bool {
+	return sameFile(fi1, fi2)
+}
+
+func (OSFileSystem) Xattrs(path string) (map[string][]byte, error) {
+	return readXattrs(path)
+}
+
+// SetFileSystem sets the FileSystem the gowatcher watches, the default is OSFileSystem.
+// A Backend only works with the OSFileSystem.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) SetFileSystem(fsys FileSystem) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fsys = fsys
+}
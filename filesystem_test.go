@@ -0,0 +1,107 @@
+package gowatcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupMemFS creates the same files and folders as setup in a MemFS.
+func setupMemFS(t testing.TB) (*MemFS, string) {
+	fsys := NewMemFS()
+	testDir := filepath.Join(string(filepath.Separator), "test")
+	dirTwo := filepath.Join(testDir, "testDirTwo")
+	if err := fsys.MkdirAll(dirTwo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"file.txt", "file_1.txt", "file_2.txt", "file_3.txt", ".dotfile"} {
+		if err := fsys.WriteFile(filepath.Join(testDir, f), []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fsys.WriteFile(filepath.Join(dirTwo, "file_recursive.txt"), []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return fsys, testDir
+}
+
+func TestMemFSAdd(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.IgnoreHiddenFiles(true)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := w.RetrieveAllNodes()
+	if len(nodes) != 7 {
+		t.Errorf("expected len(nodes) to be 7, got %d", len(nodes))
+	}
+	if _, found := nodes[filepath.Join(testDir, ".dotfile")]; found {
+		t.Error("expected to not find .dotfile")
+	}
+	if err := w.AddPath(filepath.Join(testDir, "missing"), true); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestMemFSEvents(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.HashContents(HashSHA256, 0)
+	w.SetChangePolicy(DefaultChangePolicy | ChangeXattr)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	file1 := filepath.Join(testDir, "file_1.txt")
+	file2 := filepath.Join(testDir, "file_2.txt")
+	file3 := filepath.Join(testDir, "file_3.txt")
+	renamed := filepath.Join(testDir, "testDirTwo", "moved.txt")
+	newFile := filepath.Join(testDir, "newfile.txt")
+	fileTxt := filepath.Join(testDir, "file.txt")
+
+	later := time.Now().Add(time.Hour)
+	steps := []error{
+		fsys.WriteFile(file1, []byte("content"), 0755),
+		fsys.Chtimes(file1, later),
+		fsys.Chmod(file2, 0700),
+		fsys.Rename(file3, renamed),
+		fsys.WriteFile(newFile, []byte{}, 0755),
+		fsys.Remove(fileTxt),
+		fsys.SetXattr(filepath.Join(testDir, ".dotfile"), "user.a", []byte("a")),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]Op{
+		file1:                              Write,
+		file2:                              Chmod,
+		renamed:                            Move,
+		newFile:                            Create,
+		fileTxt:                            Remove,
+		filepath.Join(testDir, ".dotfile"): Xattr,
+	}
+	for _, event := range pollOnce(w) {
+		if event.Path == testDir || event.Path == filepath.Join(testDir, "testDirTwo") {
+			continue
+		}
+		op, found := expected[event.Path]
+		if !found || op != event.Op {
+			t.Errorf("unexpected event %s", event)
+			continue
+		}
+		delete(expected, event.Path)
+	}
+	for path, op := range expected {
+		t.Errorf("expected a %s event for %s", op, path)
+	}
+}
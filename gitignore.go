@@ -0,0 +1,238 @@
+package gowatcher
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreFile is the name of the files holding the ignore rules of a directory.
+const gitignoreFile = ".gitignore"
+
+// IgnorePatterns ignores the files and directories matching the patterns, which use the .gitignore syntax:
+// a leading ! negates a pattern, a trailing / only matches directories, ** matches any number of
+// directories and a pattern containing a / is anchored to the watched root. Other patterns match
+// the names at any depth. As with git, the last matching pattern decides.
+func (w *GoWatcher) IgnorePatterns(patterns ...string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
+	for _, p := range patterns {
+		r, ok, err := parseGitignoreRule(p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			w.ignorePatterns = append(w.ignorePatterns, r)
+		}
+	}
+	return nil
+}
+
+// LoadGitignore makes the gowatcher load the .gitignore files of the watched directories, so that
+// the files ignored by git are ignored too. The rules of a .gitignore file apply to its directory
+// and take precedence over the rules of its parents and the ones set with IgnorePatterns.
+// A .gitignore file that is created, edited or removed while watching applies to its directory right away.
+func (w *GoWatcher) LoadGitignore(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
+	w.loadGitignore = enabled
+}
+
+// gitignoreRule is a single pattern of a .gitignore file.
+type gitignoreRule struct {
+	segments []string // the pattern split on /
+	negate   bool     // the pattern started with !
+	dirOnly  bool     // the pattern ended with /
+	anchored bool     // the pattern is matched against the whole relative path instead of the name
+}
+
+// parseGitignoreRule parses a line of a .gitignore file, ok is false for blank lines and comments.
+func parseGitignoreRule(line string) (r gitignoreRule, ok bool, err error) {
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are ignored unless they're escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false, nil
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return r, false, nil
+	}
+	r.segments = strings.Split(line, "/")
+	// A trailing ** matches everything inside a directory, but not the directory itself.
+	if n := len(r.segments); n > 1 && r.segments[n-1] == "**" {
+		r.segments = append(r.segments[:n-1], "*", "**")
+	}
+	for _, s := range r.segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return r, false, err
+		}
+	}
+	return r, true, nil
+}
+
+// match reports whether the rule matches a path relative to the directory of the rule.
+func (r gitignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	if !r.anchored {
+		ok, _ := path.Match(r.segments[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(r.segments, parts)
+}
+
+// ignoreRules holds the rules applying to the children of a directory. The rules of
+// the deepest directory come first and are linked to the rules of its parent.
+type ignoreRules struct {
+	parent  *ignoreRules
+	base    string // the directory the rules are relative to
+	rules   []gitignoreRule
+	filters *filterSet  // filters of the watched root, only set on the root's rules
+	source  os.FileInfo // the .gitignore file the rules were read from, nil for the root's rules
+}
+
+// match reports whether the path is ignored by the rules, the last matching rule decides.
+func (rs *ignoreRules) match(p string, isDir bool) bool {
+	for ; rs != nil; rs = rs.parent {
+		rel, err := filepath.Rel(rs.base, p)
+		if err != nil || rel == "." || outside(rel) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for i := len(rs.rules) - 1; i >= 0; i-- {
+			if rs.rules[i].match(rel, isDir) {
+				return !rs.rules[i].negate
+			}
+		}
+	}
+	return false
+}
+
+// outside reports whether a relative path leads out of its base directory.
+// A name starting with .., like the ..data entries of Kubernetes volumes, doesn't.
+func outside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// root returns the watched root the rules belong to.
+func (rs *ignoreRules) root() string {
+	for rs.parent != nil {
+		rs = rs.parent
+	}
+	return rs.base
+}
+
+// filterSet returns the filters of the watched root the rules belong to, nil for the gowatcher's ones.
+func (rs *ignoreRules) filterSet() *filterSet {
+	if rs == nil {
+		return nil
+	}
+	for rs.parent != nil {
+		rs = rs.parent
+	}
+	return rs.filters
+}
+
+// rootRules returns the rules set with IgnorePatterns, relative to a watched root. If the root has
+// its own filters, their patterns are used instead.
+func (w *GoWatcher) rootRules(root string, filters *filterSet) *ignoreRules {
+	return &ignoreRules{base: root, rules: w.filtersOf(filters).ignorePatterns, filters: filters}
+}
+
+// ignored reports whether a path is pruned from its tree, by the regexes, the
+// ignore rules applying to it, or the globs.
+func (w *GoWatcher) ignored(rules *ignoreRules, name, path string, isDir bool) bool {
+	return w.filtersOf(rules.filterSet()).ignored(rules, name, path, isDir)
+}
+
+// ignored reports whether a path is pruned from its tree by the filter set and the ignore rules.
+func (f *filterSet) ignored(rules *ignoreRules, name, path string, isDir bool) bool {
+	if f.shouldIgnore(name, path) {
+		return true
+	}
+	if rules == nil {
+		return false
+	}
+	return rules.match(path, isDir) || f.globExcluded(rules.root(), path, isDir)
+}
+
+// dirRules returns the rules applying to the children of a directory: the rules of its parent,
+// with the rules of its .gitignore file if they're loaded.
+func (w *GoWatcher) dirRules(parent *ignoreRules, dir string) *ignoreRules {
+	if !w.loadGitignore {
+		return parent
+	}
+	path := filepath.Join(dir, gitignoreFile)
+	info, err := w.fsys.Lstat(path)
+	if err != nil {
+		return parent
+	}
+	f, err := w.fsys.Open(path)
+	if err != nil {
+		return parent
+	}
+	defer f.Close()
+
+	// The rules are kept even if there are none, so that a change of the file is noticed.
+	rs := &ignoreRules{parent: parent, base: dir, source: info}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// An invalid pattern is skipped, like git does.
+		if r, ok, err := parseGitignoreRule(scanner.Text()); ok && err == nil {
+			rs.rules = append(rs.rules, r)
+		}
+	}
+	return rs
+}
+
+// gitignoreChanged reports whether the .gitignore file of a directory was created, written or
+// removed since its rules were read, according to the entries of the directory.
+func (w *GoWatcher) gitignoreChanged(node *FileNode, infoList []os.FileInfo) bool {
+	if !w.loadGitignore {
+		return false
+	}
+	var source os.FileInfo
+	if node.rules != nil && node.rules.base == node.Path {
+		source = node.rules.source
+	}
+	for _, info := range infoList {
+		if info.Name() == gitignoreFile {
+			return source == nil || !info.ModTime().Equal(source.ModTime()) || info.Size() != source.Size()
+		}
+	}
+	return source != nil
+}
+
+// attachRules sets the rules of the directories of a tree that was built without them,
+// like a tree restored from a snapshot.
+func (w *GoWatcher) attachRules(node *FileNode, parent *ignoreRules) {
+	if node == nil || node.ignored || !node.Info.IsDir() {
+		return
+	}
+	node.rules = w.dirRules(parent, node.Path)
+	for _, child := range node.Children {
+		w.attachRules(child, node.rules)
+	}
+}
@@ -0,0 +1,212 @@
+package gowatcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignoreMatch(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		ignored  bool
+	}{
+		{[]string{"*.log"}, "a/b/debug.log", false, true},
+		{[]string{"*.log"}, "..data.log", false, true},
+		{[]string{"*.log"}, "..data/debug.log", false, true},
+		{[]string{"*.log", "!keep.log"}, "a/keep.log", false, false},
+		{[]string{"*.log", "!keep.log", "*.log"}, "a/keep.log", false, true},
+		{[]string{"build/"}, "a/build", true, true},
+		{[]string{"build/"}, "a/build", false, false},
+		{[]string{"/build"}, "build", true, true},
+		{[]string{"/build"}, "a/build", true, false},
+		{[]string{"doc/*.txt"}, "doc/notes.txt", false, true},
+		{[]string{"doc/*.txt"}, "doc/server/notes.txt", false, false},
+		{[]string{"**/logs"}, "a/b/logs", true, true},
+		{[]string{"**/logs"}, "logs", true, true},
+		{[]string{"a/**/b"}, "a/b", false, true},
+		{[]string{"a/**/b"}, "a/x/y/b", false, true},
+		{[]string{"a/**"}, "a/x/y", false, true},
+		{[]string{"a/**"}, "a", true, false},
+		{[]string{"# comment", "", "\\#file"}, "#file", false, true},
+		{[]string{"\\!important"}, "!important", false, true},
+	}
+
+	root := filepath.FromSlash("/root")
+	for _, c := range cases {
+		rs := &ignoreRules{base: root}
+		for _, p := range c.patterns {
+			r, ok, err := parseGitignoreRule(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				rs.rules = append(rs.rules, r)
+			}
+		}
+		if ignored := rs.match(filepath.Join(root, filepath.FromSlash(c.path)), c.isDir); ignored != c.ignored {
+			t.Errorf("%v: expected %s to be ignored %t, got %t", c.patterns, c.path, c.ignored, ignored)
+		}
+	}
+
+	if _, _, err := parseGitignoreRule("a["); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoadGitignore(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	write := func(path, content string) {
+		if err := fsys.WriteFile(filepath.Join(testDir, filepath.FromSlash(path)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "*.txt\n!file.txt\n")
+	// The nested rules take precedence over the ones of the parent.
+	write("testDirTwo/.gitignore", "!file_recursive.txt\n/sub/\n")
+	if err := fsys.MkdirAll(filepath.Join(testDir, "testDirTwo", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.LoadGitignore(true)
+	if err := w.IgnorePatterns("/.dotfile"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{
+		testDir:                                                    true,
+		filepath.Join(testDir, ".gitignore"):                       true,
+		filepath.Join(testDir, "file.txt"):                         true,
+		filepath.Join(testDir, "testDirTwo"):                       true,
+		filepath.Join(testDir, "testDirTwo", ".gitignore"):         true,
+		filepath.Join(testDir, "testDirTwo", "file_recursive.txt"): true,
+	}
+	nodes := w.RetrieveAllNodes()
+	for path := range nodes {
+		if !expected[path] {
+			t.Errorf("unexpected node %s", path)
+		}
+	}
+	for path := range expected {
+		if _, found := nodes[path]; !found {
+			t.Errorf("expected node %s", path)
+		}
+	}
+
+	// The rules apply to the nodes created later too.
+	write("new.txt", "")
+	write("testDirTwo/new.txt", "")
+	write("new.go", "")
+	var created []string
+	for _, event := range pollOnce(w) {
+		if event.Op == Create {
+			created = append(created, event.Path)
+		}
+	}
+	newGo := filepath.Join(testDir, "new.go")
+	if len(created) != 1 || created[0] != newGo {
+		t.Errorf("expected a single create event for %s, got %v", newGo, created)
+	}
+}
+
+func TestIgnoreInFileReplacedByDir(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	w := New()
+	w.SetFileSystem(fsys)
+	if err := w.AddPathWithOptions(testDir, Options{
+		Recursive:      true,
+		IgnorePatterns: []string{"*.log"},
+		IgnoreNames:    []string{"^skip$"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The directory replacing a watched file gets the ignore rules of its parent.
+	dir := filepath.Join(testDir, "file.txt")
+	if err := fsys.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	kept := filepath.Join(dir, "kept.txt")
+	for _, path := range []string{kept, filepath.Join(dir, "a.log"), filepath.Join(dir, "skip")} {
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := false
+	for _, event := range pollOnce(w) {
+		if event.Op == Create && event.Path != kept {
+			t.Errorf("unexpected event %s", event)
+		}
+		created = created || event.Op == Create
+	}
+	if !created {
+		t.Errorf("expected a create event for %s", kept)
+	}
+}
+
+func TestGitignoreChanged(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	write := func(path, content string) {
+		if err := fsys.WriteFile(filepath.Join(testDir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var w *GoWatcher
+	watched := func(path string) bool {
+		node, found := w.RetrieveAllNodes()[filepath.Join(testDir, path)]
+		return found && !node.ignored
+	}
+	write("a.log", "")
+
+	w = New()
+	w.SetFileSystem(fsys)
+	w.LoadGitignore(true)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The files ignored by a new .gitignore are pruned without events.
+	write(".gitignore", "*.log\n")
+	write("b.log", "")
+	for _, event := range pollOnce(w) {
+		if event.Path != testDir && event.Name() != ".gitignore" {
+			t.Errorf("unexpected event %s", event)
+		}
+	}
+	if watched("a.log") || watched("b.log") {
+		t.Error("expected the .log files to be ignored")
+	}
+
+	// The files aren't ignored anymore once the .gitignore is edited.
+	write(".gitignore", "*.tmp\n")
+	for _, event := range pollOnce(w) {
+		if event.Path != testDir && event.Name() != ".gitignore" {
+			t.Errorf("unexpected event %s", event)
+		}
+	}
+	if !watched("a.log") || !watched("b.log") {
+		t.Error("expected the .log files to be watched")
+	}
+
+	write(".gitignore", "a.log\n")
+	pollOnce(w)
+	if watched("a.log") {
+		t.Error("expected a.log to be ignored")
+	}
+	if err := fsys.Remove(filepath.Join(testDir, ".gitignore")); err != nil {
+		t.Fatal(err)
+	}
+	pollOnce(w)
+	if !watched("a.log") {
+		t.Error("expected a.log to be watched once the .gitignore is removed")
+	}
+}
@@ -0,0 +1,217 @@
+package gowatcher
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBadGlob occurs when a glob pattern has unbalanced braces.
+var ErrBadGlob = errors.New("error: glob has unbalanced braces")
+
+// IncludeGlob only watches the files and directories whose path relative to the watched root matches
+// one of the patterns. The patterns use / as separator and the path.Match syntax, extended with ** which
+// matches any number of directories and {a,b} which matches either alternative. The directories that
+// can't contain a matching path aren't scanned.
+func (w *GoWatcher) IncludeGlob(patterns ...string) error {
+	globs, err := compileGlobs(patterns)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
+	w.includeGlobs = append(w.includeGlobs, globs...)
+	return nil
+}
+
+// ExcludeGlob doesn't watch the files and directories whose path relative to the watched root matches
+// one of the patterns, which use the same syntax as IncludeGlob. The excluded directories aren't scanned.
+func (w *GoWatcher) ExcludeGlob(patterns ...string) error {
+	globs, err := compileGlobs(patterns)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
+	w.excludeGlobs = append(w.excludeGlobs, globs...)
+	return nil
+}
+
+// glob is a compiled glob pattern, split on /.
+type glob []string
+
+func compileGlobs(patterns []string) ([]glob, error) {
+	var globs []glob
+	for _, p := range patterns {
+		expanded, err := expandBraces(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expanded {
+			g := glob(strings.Split(strings.Trim(e, "/"), "/"))
+			for _, s := range g {
+				if _, err := path.Match(s, ""); err != nil {
+					return nil, err
+				}
+			}
+			globs = append(globs, g)
+		}
+	}
+	return globs, nil
+}
+
+// expandBraces returns every alternative of a pattern containing {a,b} groups.
+func expandBraces(p string) ([]string, error) {
+	start := strings.IndexByte(p, '{')
+	if start < 0 {
+		if strings.IndexByte(p, '}') >= 0 {
+			return nil, ErrBadGlob
+		}
+		return []string{p}, nil
+	}
+	if strings.IndexByte(p[:start], '}') >= 0 {
+		return nil, ErrBadGlob
+	}
+	// Find the matching brace and the commas of the group.
+	depth, end := 0, -1
+	var alternatives []string
+	last := start + 1
+	for i := start; i < len(p) && end < 0; i++ {
+		switch p[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+				alternatives = append(alternatives, p[last:i])
+			}
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, p[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if end < 0 {
+		return nil, ErrBadGlob
+	}
+	var expanded []string
+	for _, a := range alternatives {
+		rest, err := expandBraces(p[:start] + a + p[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, rest...)
+	}
+	return expanded, nil
+}
+
+// match reports whether the glob matches a path split on /.
+func (g glob) match(parts []string) bool {
+	return matchSegments(g, parts)
+}
+
+// matchPrefix reports whether a path under the directory split in parts could match the glob.
+func (g glob) matchPrefix(parts []string) bool {
+	pattern := []string(g)
+	for len(parts) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(pattern) > 0
+}
+
+// matchSegments matches the segments of a path against the segments of a pattern,
+// where a ** segment matches any number of path segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// relParts splits the path relative to the root on /, it returns nil for the root itself.
+func relParts(root, p string) []string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// globExcluded reports whether the include and exclude globs prune the path from the tree of the root.
+// The directories that could contain an included path are kept.
+func (f *filterSet) globExcluded(root, p string, isDir bool) bool {
+	if len(f.includeGlobs) == 0 && len(f.excludeGlobs) == 0 {
+		return false
+	}
+	parts := relParts(root, p)
+	if parts == nil {
+		return false
+	}
+	for _, g := range f.excludeGlobs {
+		if g.match(parts) {
+			return true
+		}
+	}
+	if len(f.includeGlobs) == 0 {
+		return false
+	}
+	for _, g := range f.includeGlobs {
+		if g.match(parts) || isDir && g.matchPrefix(parts) {
+			return false
+		}
+	}
+	return true
+}
+
+// globNotice reports whether an event is sent according to the include and exclude globs.
+func (f *filterSet) globNotice(e Event) bool {
+	if len(f.includeGlobs) == 0 && len(f.excludeGlobs) == 0 {
+		return true
+	}
+	if e.Root == "" {
+		return len(f.includeGlobs) == 0
+	}
+	parts := relParts(e.Root, e.Path)
+	for _, g := range f.excludeGlobs {
+		if g.match(parts) {
+			return false
+		}
+	}
+	if len(f.includeGlobs) == 0 {
+		return true
+	}
+	for _, g := range f.includeGlobs {
+		if g.match(parts) {
+			return true
+		}
+	}
+	return false
+}
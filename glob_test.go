@@ -0,0 +1,106 @@
+package gowatcher
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/gowatcher/main.go", true},
+		{"cmd/**", "cmd", true},
+		{"cmd/**", "cmd/gowatcher/main.go", true},
+		{"cmd/**/main.go", "cmd/main.go", true},
+		{"cmd/**/main.go", "cmd/a/b/main.go", true},
+		{"cmd/**/main.go", "main.go", false},
+		{"*.{go,md}", "README.md", true},
+		{"{cmd,example}/**/*.go", "example/basics/main.go", true},
+		{"{cmd,example}/**/*.go", "vendor/main.go", false},
+		{"file_?.txt", "file_1.txt", true},
+	}
+	for _, c := range cases {
+		globs, err := compileGlobs([]string{c.pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := false
+		for _, g := range globs {
+			match = match || g.match(strings.Split(c.path, "/"))
+		}
+		if match != c.match {
+			t.Errorf("expected %s to match %s %t, got %t", c.pattern, c.path, c.match, match)
+		}
+	}
+
+	for _, pattern := range []string{"[a", "{a,b", "a}"} {
+		if _, err := compileGlobs([]string{pattern}); err == nil {
+			t.Errorf("expected an error for %s", pattern)
+		}
+	}
+}
+
+func TestIncludeExcludeGlob(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	if err := fsys.MkdirAll(filepath.Join(testDir, "skipped"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.SetFileSystem(fsys)
+	if err := w.IncludeGlob("**/*.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ExcludeGlob("file_{1,2}.txt", "skipped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ExcludeGlob("[a"); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	dirTwo := filepath.Join(testDir, "testDirTwo")
+	expected := map[string]bool{
+		testDir:                              true,
+		filepath.Join(testDir, "file.txt"):   true,
+		filepath.Join(testDir, "file_3.txt"): true,
+		dirTwo:                               true,
+		filepath.Join(dirTwo, "file_recursive.txt"): true,
+	}
+	nodes := w.RetrieveAllNodes()
+	for path := range nodes {
+		if !expected[path] {
+			t.Errorf("unexpected node %s", path)
+		}
+	}
+	if len(nodes) != len(expected) {
+		t.Errorf("expected %d nodes, got %d", len(expected), len(nodes))
+	}
+
+	// The events are filtered with the same globs: the directories are scanned, but only the
+	// included files are notified.
+	newFile := filepath.Join(dirTwo, "new.txt")
+	for _, path := range []string{newFile, filepath.Join(dirTwo, "new.md"), filepath.Join(testDir, "file_1.txt")} {
+		if err := fsys.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var events []Event
+	for _, event := range pollOnce(w) {
+		if w.globNotice(event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 1 || events[0].Op != Create || events[0].Path != newFile {
+		t.Errorf("expected a single create event for %s, got %v", newFile, events)
+	}
+}
@@ -0,0 +1,91 @@
+package gowatcher
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// A HashAlgorithm is the algorithm used to compute the digest
+// of a file's content when content hashing is enabled.
+type HashAlgorithm uint8
+
+// Hash algorithms
+const (
+	HashSHA256 HashAlgorithm = iota
+	HashSHA1
+	HashMD5
+	HashCRC32
+)
+
+var hashAlgorithms = map[HashAlgorithm]string{
+	HashSHA256: "SHA256",
+	HashSHA1:   "SHA1",
+	HashMD5:    "MD5",
+	HashCRC32:  "CRC32",
+}
+
+// String prints the string version of the HashAlgorithm consts
+func (a HashAlgorithm) String() string {
+	if name, found := hashAlgorithms[a]; found {
+		return name
+	}
+	return "???"
+}
+
+func (a HashAlgorithm) new() hash.Hash {
+	switch a {
+	case HashSHA1:
+		return sha1.New()
+	case HashMD5:
+		return md5.New()
+	case HashCRC32:
+		return crc32.NewIEEE()
+	default:
+		return sha256.New()
+	}
+}
+
+// HashContents makes the gowatcher hash the content of regular files and only send a Write event
+// when the digest changed, instead of relying on the ModTime alone. Files that are bigger than
+// maxSize bytes are not hashed and fall back to the ModTime comparison. If maxSize is less than 1,
+// there is no limit.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) HashContents(algorithm HashAlgorithm, maxSize int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hashContents = true
+	w.hashAlgorithm = algorithm
+	w.hashMaxSize = maxSize
+}
+
+// digest returns the digest of the file's content, or nil if the file shouldn't or couldn't be hashed.
+func (w *GoWatcher) digest(path string, info os.FileInfo) []byte {
+	if !w.hashContents || !info.Mode().IsRegular() {
+		return nil
+	}
+	if w.hashMaxSize > 0 && info.Size() > w.hashMaxSize {
+		return nil
+	}
+	f, err := w.fsys.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if w.hashMaxSize > 0 {
+		// The file could have grown since it was stat'ed.
+		r = io.LimitReader(f, w.hashMaxSize+1)
+	}
+	h := w.hashAlgorithm.new()
+	n, err := io.Copy(h, r)
+	if err != nil || (w.hashMaxSize > 0 && n > w.hashMaxSize) {
+		return nil
+	}
+	return h.Sum(nil)
+}
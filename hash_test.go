@@ -0,0 +1,103 @@
+package gowatcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashAlgorithmString(t *testing.T) {
+	testCases := []struct {
+		algorithm HashAlgorithm
+		expected  string
+	}{
+		{HashSHA256, "SHA256"},
+		{HashSHA1, "SHA1"},
+		{HashMD5, "MD5"},
+		{HashCRC32, "CRC32"},
+		{HashAlgorithm(10), "???"},
+	}
+
+	for _, tc := range testCases {
+		if tc.algorithm.String() != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, tc.algorithm.String())
+		}
+	}
+}
+
+func TestHashContents(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.HashContents(HashSHA256, 0)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	if w.RetrieveAllNodes()[fileTxt].Digest == nil {
+		t.Fatalf("expected %s to have a digest", fileTxt)
+	}
+
+	// Touching a file without changing its content must not be a write.
+	later := time.Now().Add(time.Hour)
+	if err := chtimes(fileTxt, later); err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range pollOnce(w) {
+		if event.Path == fileTxt {
+			t.Errorf("expected no event for %s, got %s", fileTxt, event)
+		}
+	}
+
+	// Changing the content without changing the ModTime must be a write.
+	if err := ioutil.WriteFile(fileTxt, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := chtimes(fileTxt, later); err != nil {
+		t.Fatal(err)
+	}
+	var writes []Event
+	for _, event := range pollOnce(w) {
+		if event.Path == fileTxt && event.Op == Write {
+			writes = append(writes, event)
+		}
+	}
+	if len(writes) != 1 {
+		t.Fatalf("expected 1 write event for %s, got %d", fileTxt, len(writes))
+	}
+	if writes[0].Digest == nil {
+		t.Error("expected the write event to carry a digest")
+	}
+}
+
+func TestHashContentsMaxSize(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	if err := ioutil.WriteFile(fileTxt, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.HashContents(HashCRC32, 4)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := w.RetrieveAllNodes()
+	if nodes[fileTxt].Digest != nil {
+		t.Errorf("expected %s to not be hashed", fileTxt)
+	}
+	if nodes[filepath.Join(testDir, "file_1.txt")].Digest == nil {
+		t.Error("expected file_1.txt to be hashed")
+	}
+}
+
+func chtimes(path string, t time.Time) error {
+	return os.Chtimes(path, t, t)
+}
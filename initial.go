@@ -0,0 +1,44 @@
+package gowatcher
+
+import (
+	"sort"
+)
+
+// SetInitialEvents makes AddPath and its variants send an Exists event for the added path and
+// every file and directory found below it, followed by a Ready event for the path once all of
+// them were sent. The events are sent by the next polling cycle, before its other events.
+// A path added with SetPendingPaths that doesn't exist yet sends Create events when it appears.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) SetInitialEvents(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.initialEvents = enabled
+}
+
+// listTree queues the Exists events of every node of a tree, sorted by path, and its Ready event.
+func (w *GoWatcher) listTree(t *fileTree) {
+	nodes := t.root.RetrieveAllNodes()
+	paths := make([]string, 0, len(nodes))
+	for path, node := range nodes {
+		if !node.ignored {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		node := nodes[path]
+		w.initial = append(w.initial, t.event(Event{Op: Exists, Path: path, FileInfo: node.Info, Digest: node.Digest}))
+	}
+	w.initial = append(w.initial, t.event(Event{Op: Ready, Path: t.path, FileInfo: t.root.Info}))
+}
+
+// queueInitial queues the Exists and Ready events before the other events of a cycle, it
+// returns their number.
+func (w *GoWatcher) queueInitial(c *pollCycle) int {
+	for _, e := range w.initial {
+		c.send(e)
+	}
+	n := len(w.initial)
+	w.initial = nil
+	return n
+}
@@ -0,0 +1,46 @@
+package gowatcher
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInitialEvents(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.IgnoreHiddenFiles(true)
+	w.SetInitialEvents(true)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := time.Now()
+	events := pollOnce(w)
+	if len(events) != 8 {
+		t.Fatalf("expected 8 events, got %v", events)
+	}
+	nodes := w.RetrieveAllNodes()
+	for _, e := range events[:7] {
+		if _, found := nodes[e.Path]; e.Op != Exists || !found {
+			t.Errorf("expected an exists event for a watched path, got %v", e)
+		}
+		// The files were found by AddPath, not by the cycle sending the events.
+		if e.Time.After(listed) {
+			t.Errorf("expected %s to be detected before %v, got %v", e.Path, listed, e.Time)
+		}
+	}
+	if e := events[7]; e.Op != Ready || e.Path != testDir {
+		t.Errorf("expected a ready event for %s, got %v", testDir, e)
+	}
+	if _, found := nodes[filepath.Join(testDir, ".dotfile")]; found {
+		t.Error("expected to not find .dotfile")
+	}
+
+	// The initial listing is only sent once.
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+}
@@ -0,0 +1,170 @@
+// +build linux
+
+package gowatcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Magic numbers of the filesystems on which inotify doesn't report remote changes.
+var inotifyUnsupportedFS = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xfe534d42: "smb2",
+	0xff534d42: "cifs",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+}
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF |
+	syscall.IN_DONT_FOLLOW
+
+// inotifyBackend is a Backend using the Linux inotify API.
+type inotifyBackend struct {
+	fd      int
+	file    *os.File
+	changes chan string
+	errors  chan error
+	done    chan struct{}
+	once    sync.Once
+
+	// mu protects the following.
+	mu      sync.Mutex
+	watches map[int]string // watched paths by watch descriptor
+	paths   map[string]int // watch descriptors by watched path
+}
+
+// NewInotifyBackend creates a Backend that uses inotify to notice changes.
+// Paths on network or FUSE filesystems, and paths that can't be watched because
+// the inotify watch limit was reached, fall back to polling.
+func NewInotifyBackend() (Backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	b := &inotifyBackend{
+		fd: fd,
+		// As the fd is non-blocking, reading the file uses the runtime's poller
+		// and closing it unblocks the pending read.
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changes: make(chan string, 1024),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+		watches: make(map[int]string),
+		paths:   make(map[string]int),
+	}
+	go b.read()
+	return b, nil
+}
+
+func (b *inotifyBackend) Add(path string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	if fs, found := inotifyUnsupportedFS[uint32(st.Type)]; found {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: errors.New("unsupported filesystem " + fs)}
+	}
+
+	wd, err := syscall.InotifyAddWatch(b.fd, path, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Watching a moved directory again returns the same descriptor.
+	if old, found := b.watches[wd]; found {
+		delete(b.paths, old)
+	}
+	b.watches[wd] = path
+	b.paths[path] = wd
+	return nil
+}
+
+func (b *inotifyBackend) Remove(path string) error {
+	b.mu.Lock()
+	wd, found := b.paths[path]
+	if found {
+		delete(b.paths, path)
+		delete(b.watches, wd)
+	}
+	b.mu.Unlock()
+	if !found {
+		return nil
+	}
+	// The watch is already gone if the path was removed.
+	if _, err := syscall.InotifyRmWatch(b.fd, uint32(wd)); err != nil && err != syscall.EINVAL {
+		return &os.PathError{Op: "inotify_rm_watch", Path: path, Err: err}
+	}
+	return nil
+}
+
+func (b *inotifyBackend) Changes() <-chan string {
+	return b.changes
+}
+
+func (b *inotifyBackend) Errors() <-chan error {
+	return b.errors
+}
+
+func (b *inotifyBackend) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.done)
+		err = b.file.Close()
+	})
+	return err
+}
+
+func (b *inotifyBackend) read() {
+	buf := make([]byte, (syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)*64)
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			select {
+			case <-b.done:
+			case b.errors <- err:
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				b.notify("")
+				continue
+			}
+			b.mu.Lock()
+			path := b.watches[int(raw.Wd)]
+			if raw.Mask&syscall.IN_IGNORED != 0 && path != "" {
+				delete(b.watches, int(raw.Wd))
+				delete(b.paths, path)
+			}
+			b.mu.Unlock()
+			if path == "" || raw.Mask&syscall.IN_IGNORED != 0 {
+				continue
+			}
+			// A watched path that's gone must be noticed by its parent too.
+			if raw.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 {
+				b.notify(filepath.Dir(path))
+			}
+			b.notify(path)
+		}
+	}
+}
+
+func (b *inotifyBackend) notify(path string) {
+	select {
+	case <-b.done:
+	case b.changes <- path:
+	}
+}
@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package gowatcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInotifyBackend(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	b, err := NewInotifyBackend()
+	if err != nil {
+		t.Skipf("inotify is not available: %s", err)
+	}
+	w := startBackendTest(t, testDir, b)
+	defer w.Close()
+	if !w.fileTrees[testDir].covered {
+		t.Skipf("inotify can't watch %s", testDir)
+	}
+
+	newFile := filepath.Join(testDir, "testDirTwo", "newfile.txt")
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectCreate(t, w, newFile)
+
+	// New directories are watched too.
+	newDir := filepath.Join(testDir, "newdir")
+	if err := os.Mkdir(newDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectCreate(t, w, newDir)
+
+	nestedFile := filepath.Join(newDir, "nested.txt")
+	if err := ioutil.WriteFile(nestedFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectCreate(t, w, nestedFile)
+}
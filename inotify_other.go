@@ -0,0 +1,8 @@
+// +build !linux
+
+package gowatcher
+
+// NewInotifyBackend returns ErrBackendUnsupported, as inotify is only available on Linux.
+func NewInotifyBackend() (Backend, error) {
+	return nil, ErrBackendUnsupported
+}
@@ -0,0 +1,75 @@
+//go:build go1.16
+// +build go1.16
+
+package gowatcher
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ioFileSystem is a FileSystem reading an fs.FS.
+type ioFileSystem struct {
+	fsys fs.FS
+}
+
+// NewIOFileSystem returns a FileSystem that reads the given fs.FS. The root of the
+// fs.FS is the root of the FileSystem, "/a/b" is read as "a/b" from the fs.FS.
+// As an fs.FS has no Lstat, symlinks are followed and files are never considered the same,
+// so a rename is reported as a Remove and a Create.
+func NewIOFileSystem(fsys fs.FS) FileSystem {
+	return ioFileSystem{fsys: fsys}
+}
+
+// name converts an absolute path to an fs.FS name.
+func (f ioFileSystem) name(p string) string {
+	p = strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+func (f ioFileSystem) Abs(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p), nil
+	}
+	return filepath.Join(string(filepath.Separator), p), nil
+}
+
+func (f ioFileSystem) Lstat(p string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, f.name(p))
+}
+
+func (f ioFileSystem) ReadDir(p string) ([]os.FileInfo, error) {
+	entries, err := fs.ReadDir(f.fsys, f.name(p))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// The entry was removed since the directory was read.
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (f ioFileSystem) Open(p string) (io.ReadCloser, error) {
+	return f.fsys.Open(f.name(p))
+}
+
+func (f ioFileSystem) IsHidden(p string) (bool, error) {
+	return strings.HasPrefix(path.Base(f.name(p)), "."), nil
+}
+
+func (f ioFileSystem) SameFile(fi1, fi2 os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,35 @@
+//go:build go1.16
+// +build go1.16
+
+package gowatcher
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestIOFileSystem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/file.txt":     {Data: []byte("a"), ModTime: time.Now()},
+		"dir/sub/file.txt": {Data: []byte("b"), ModTime: time.Now()},
+	}
+
+	w := New()
+	w.SetFileSystem(NewIOFileSystem(fsys))
+	dir := filepath.Join(string(filepath.Separator), "dir")
+	if err := w.AddPath(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.RetrieveAllNodes()) != 4 {
+		t.Errorf("expected len(nodes) to be 4, got %d", len(w.RetrieveAllNodes()))
+	}
+
+	fsys["dir/sub/new.txt"] = &fstest.MapFile{ModTime: time.Now()}
+	newFile := filepath.Join(dir, "sub", "new.txt")
+	events := pollOnce(w)
+	if len(events) != 1 || events[0].Op != Create || events[0].Path != newFile {
+		t.Errorf("expected a create event for %s, got %v", newFile, events)
+	}
+}
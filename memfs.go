@@ -0,0 +1,305 @@
+package gowatcher
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory FileSystem, which makes it possible to run the
+// gowatcher against a virtual tree, in tests for example.
+// Files whose name starts with a dot are hidden.
+type MemFS struct {
+	mu   sync.RWMutex
+	root *memFile
+}
+
+// memFile is a file or directory of a MemFS, a renamed file keeps its memFile.
+type memFile struct {
+	mode     os.FileMode
+	modTime  time.Time
+	data     []byte
+	xattrs   map[string][]byte
+	children map[string]*memFile // nil for files
+}
+
+// NewMemFS creates an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{root: newMemFile(os.ModeDir | 0755)}
+}
+
+func newMemFile(mode os.FileMode) *memFile {
+	f := &memFile{mode: mode, modTime: time.Now()}
+	if mode.IsDir() {
+		f.children = make(map[string]*memFile)
+	}
+	return f
+}
+
+func (f *memFile) info(name string) os.FileInfo {
+	return &fileInfo{
+		name:    name,
+		size:    int64(len(f.data)),
+		mode:    f.mode,
+		modTime: f.modTime,
+		sys:     f,
+		dir:     f.mode.IsDir(),
+	}
+}
+
+// split returns the names of the elements of an absolute path.
+func (m *MemFS) split(path string) []string {
+	path = filepath.Clean(path)
+	path = strings.TrimPrefix(path, filepath.VolumeName(path))
+	path = strings.Trim(path, string(filepath.Separator))
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, string(filepath.Separator))
+}
+
+func (m *MemFS) lookup(op, path string) (*memFile, error) {
+	f := m.root
+	for _, name := range m.split(path) {
+		if f.children == nil {
+			return nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+		}
+		if f = f.children[name]; f == nil {
+			return nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+		}
+	}
+	return f, nil
+}
+
+// parent returns the directory containing a path and the path's name.
+func (m *MemFS) parent(op, path string) (*memFile, string, error) {
+	names := m.split(path)
+	if len(names) == 0 {
+		return nil, "", &os.PathError{Op: op, Path: path, Err: os.ErrInvalid}
+	}
+	dir, err := m.lookup(op, filepath.Dir(filepath.Clean(path)))
+	if err != nil {
+		return nil, "", err
+	}
+	if dir.children == nil {
+		return nil, "", &os.PathError{Op: op, Path: path, Err: os.ErrInvalid}
+	}
+	return dir, names[len(names)-1], nil
+}
+
+func (m *MemFS) Abs(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	return filepath.Join(string(filepath.Separator), path), nil
+}
+
+func (m *MemFS) Lstat(path string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, err := m.lookup("lstat", path)
+	if err != nil {
+		return nil, err
+	}
+	return f.info(filepath.Base(path)), nil
+}
+
+func (m *MemFS) ReadDir(path string) ([]os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, err := m.lookup("readdir", path)
+	if err != nil {
+		return nil, err
+	}
+	if f.children == nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrInvalid}
+	}
+	infos := make([]os.FileInfo, 0, len(f.children))
+	for name, child := range f.children {
+		infos = append(infos, child.info(name))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (m *MemFS) Open(path string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, err := m.lookup("open", path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (m *MemFS) IsHidden(path string) (bool, error) {
+	return strings.HasPrefix(filepath.Base(path), "."), nil
+}
+
+func (m *MemFS) SameFile(fi1, fi2 os.FileInfo) bool {
+	f1, ok1 := fi1.Sys().(*memFile)
+	f2, ok2 := fi2.Sys().(*memFile)
+	return ok1 && ok2 && f1 == f2
+}
+
+func (m *MemFS) Xattrs(path string) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, err := m.lookup("getxattr", path)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(map[string][]byte, len(f.xattrs))
+	for k, v := range f.xattrs {
+		attrs[k] = v
+	}
+	return attrs, nil
+}
+
+// create adds a new file to its parent directory.
+func (m *MemFS) create(op, path string, mode os.FileMode) (*memFile, error) {
+	dir, name, err := m.parent(op, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, found := dir.children[name]; found {
+		return nil, &os.PathError{Op: op, Path: path, Err: os.ErrExist}
+	}
+	f := newMemFile(mode)
+	dir.children[name] = f
+	dir.modTime = f.modTime
+	return f, nil
+}
+
+// Mkdir creates a directory.
+func (m *MemFS) Mkdir(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.create("mkdir", path, os.ModeDir|perm.Perm())
+	return err
+}
+
+// MkdirAll creates a directory and any missing parent.
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir := string(filepath.Separator)
+	for _, name := range m.split(path) {
+		dir = filepath.Join(dir, name)
+		f, err := m.lookup("mkdir", dir)
+		if err == nil {
+			if f.children == nil {
+				return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+			}
+			continue
+		}
+		if _, err := m.create("mkdir", dir, os.ModeDir|perm.Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile writes data to a file, creating it if necessary.
+func (m *MemFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := m.lookup("open", path)
+	if err != nil {
+		if f, err = m.create("open", path, perm.Perm()); err != nil {
+			return err
+		}
+	} else if f.children != nil {
+		return &os.PathError{Op: "open", Path: path, Err: os.ErrInvalid}
+	}
+	f.data = append([]byte(nil), data...)
+	f.modTime = time.Now()
+	return nil
+}
+
+// Remove removes a file or a directory and everything it contains.
+func (m *MemFS) Remove(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir, name, err := m.parent("remove", path)
+	if err != nil {
+		return err
+	}
+	if _, found := dir.children[name]; !found {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+	delete(dir.children, name)
+	dir.modTime = time.Now()
+	return nil
+}
+
+// Rename moves a file or directory, replacing the destination if it's a file.
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldDir, oldName, err := m.parent("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	f, found := oldDir.children[oldName]
+	if !found {
+		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+	}
+	newDir, newName, err := m.parent("rename", newpath)
+	if err != nil {
+		return err
+	}
+	if dst, found := newDir.children[newName]; found && dst.children != nil {
+		return &os.PathError{Op: "rename", Path: newpath, Err: os.ErrExist}
+	}
+	delete(oldDir.children, oldName)
+	newDir.children[newName] = f
+	now := time.Now()
+	oldDir.modTime, newDir.modTime = now, now
+	return nil
+}
+
+// Chmod changes the permission bits of a file.
+func (m *MemFS) Chmod(path string, mode os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := m.lookup("chmod", path)
+	if err != nil {
+		return err
+	}
+	f.mode = f.mode&^os.ModePerm | mode.Perm()
+	return nil
+}
+
+// Chtimes changes the modification time of a file.
+func (m *MemFS) Chtimes(path string, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := m.lookup("chtimes", path)
+	if err != nil {
+		return err
+	}
+	f.modTime = mtime
+	return nil
+}
+
+// SetXattr sets an extended attribute of a file.
+func (m *MemFS) SetXattr(path, name string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := m.lookup("setxattr", path)
+	if err != nil {
+		return err
+	}
+	if f.xattrs == nil {
+		f.xattrs = make(map[string][]byte)
+	}
+	f.xattrs[name] = append([]byte(nil), value...)
+	return nil
+}
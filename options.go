@@ -0,0 +1,130 @@
+package gowatcher
+
+import (
+	"regexp"
+	"time"
+)
+
+// Options are the settings of a path added with AddPathWithOptions. They replace the filters,
+// ops and hidden-file policy set on the gowatcher for the files of that path, and its events
+// are filtered with them instead of the gowatcher's ones.
+type Options struct {
+	Recursive bool // watch the directories recursively
+	MaxDepth  *int // levels of directories watched below the path like AddPathWithDepth, used instead of Recursive if set
+
+	Interval time.Duration // the path's own polling interval, 0 for the interval passed to Start
+	Priority int           // paths with a higher priority are polled first
+
+	IgnoreHidden   bool     // ignore hidden files
+	Ops            []Op     // only send these ops, or all of them if empty
+	IgnoreNames    []string // regexes of the names to ignore, like Ignore
+	IgnorePaths    []string // regexes of the paths to ignore, like IgnorePath
+	FilterNames    []string // regexes of the names to notify, like FilterName
+	FilterPaths    []string // regexes of the paths to notify, like FilterPath
+	IgnorePatterns []string // patterns in the .gitignore syntax, like IgnorePatterns
+	IncludeGlobs   []string // globs of the paths to include, like IncludeGlob
+	ExcludeGlobs   []string // globs of the paths to exclude, like ExcludeGlob
+}
+
+// filterSet holds the filters applied to the files of a watched path and to its events.
+type filterSet struct {
+	nameFilters    []*regexp.Regexp
+	nameIgnores    []*regexp.Regexp
+	pathFilters    []*regexp.Regexp
+	pathIgnores    []*regexp.Regexp
+	ops            map[Op]struct{} // Op filtering.
+	ignoreHidden   bool            // ignore hidden files or not.
+	ignorePatterns []gitignoreRule // rules in the .gitignore syntax, relative to the watched roots.
+	includeGlobs   []glob          // only watch the paths relative to the roots matching these.
+	excludeGlobs   []glob          // don't watch the paths relative to the roots matching these.
+}
+
+// AddPathWithOptions adds either a single file or directory to the file tree, with its own options.
+// It returns an error if one of the regexes, patterns or globs is invalid.
+func (w *GoWatcher) AddPathWithOptions(path string, opts Options) error {
+	if opts.Interval < 0 {
+		return ErrDurationTooShort
+	}
+	f, err := opts.compile()
+	if err != nil {
+		return err
+	}
+	depth := maxDepth(opts.Recursive)
+	if opts.MaxDepth != nil {
+		depth = *opts.MaxDepth
+	}
+	return w.addPath(path, depth, opts.Interval, opts.Priority, f, &opts)
+}
+
+// compile returns the filter set of the options.
+func (o *Options) compile() (*filterSet, error) {
+	f := &filterSet{ignoreHidden: o.IgnoreHidden}
+	var err error
+	if f.nameIgnores, err = compileRegexps(o.IgnoreNames); err != nil {
+		return nil, err
+	}
+	if f.pathIgnores, err = compileRegexps(o.IgnorePaths); err != nil {
+		return nil, err
+	}
+	if f.nameFilters, err = compileRegexps(o.FilterNames); err != nil {
+		return nil, err
+	}
+	if f.pathFilters, err = compileRegexps(o.FilterPaths); err != nil {
+		return nil, err
+	}
+	if f.includeGlobs, err = compileGlobs(o.IncludeGlobs); err != nil {
+		return nil, err
+	}
+	if f.excludeGlobs, err = compileGlobs(o.ExcludeGlobs); err != nil {
+		return nil, err
+	}
+	for _, p := range o.IgnorePatterns {
+		r, ok, err := parseGitignoreRule(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			f.ignorePatterns = append(f.ignorePatterns, r)
+		}
+	}
+	if len(o.Ops) > 0 {
+		f.ops = make(map[Op]struct{})
+		for _, op := range o.Ops {
+			f.ops[op] = struct{}{}
+		}
+	}
+	return f, nil
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	var regs []*regexp.Regexp
+	for _, e := range exprs {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+		regs = append(regs, re)
+	}
+	return regs, nil
+}
+
+// notice reports whether an event passes the ops, the name and path filters and the globs.
+func (f *filterSet) notice(e Event) bool {
+	if len(f.ops) > 0 { // Filter Ops.
+		if _, found := f.ops[e.Op]; !found {
+			return false
+		}
+	}
+	if e.Op == Ready {
+		return true
+	}
+	return f.shouldNotice(e.Name(), e.Path) && f.globNotice(e)
+}
+
+// filtersOf returns the filter set of a root, or the gowatcher's one if the root has none.
+func (w *GoWatcher) filtersOf(f *filterSet) *filterSet {
+	if f == nil {
+		return &w.filterSet
+	}
+	return f
+}
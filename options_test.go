@@ -0,0 +1,143 @@
+package gowatcher
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddPathWithOptions(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	other := filepath.Join(string(filepath.Separator), "other")
+	if err := fsys.MkdirAll(filepath.Join(other, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{".dotfile", "file.md", filepath.Join("sub", "file.txt")} {
+		if err := fsys.WriteFile(filepath.Join(other, f), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New()
+	w.SetFileSystem(fsys)
+	if err := w.AddPathWithOptions(testDir, Options{FilterNames: []string{"[a"}}); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+	if err := w.AddPathWithOptions(testDir, Options{
+		Recursive:    true,
+		IgnoreHidden: true,
+		IgnoreNames:  []string{`^file_1\.txt$`},
+		FilterNames:  []string{`\.txt$`},
+		Ops:          []Op{Create},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPath(other, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hidden files and the ignored names are only pruned from the first root,
+	// which is recursive while the second one isn't.
+	nodes := w.RetrieveAllNodes()
+	for _, path := range []string{
+		filepath.Join(testDir, ".dotfile"),
+		filepath.Join(testDir, "file_1.txt"),
+		filepath.Join(other, "sub", "file.txt"),
+	} {
+		if _, found := nodes[path]; found {
+			t.Errorf("expected to not find %s", path)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(testDir, "testDirTwo", "file_recursive.txt"),
+		filepath.Join(other, ".dotfile"),
+		filepath.Join(other, "sub"),
+	} {
+		if _, found := nodes[path]; !found {
+			t.Errorf("expected to find %s", path)
+		}
+	}
+
+	// The events of each root are filtered with its own options.
+	created := []string{
+		filepath.Join(testDir, "new.txt"),
+		filepath.Join(testDir, "new.md"),
+		filepath.Join(other, "new.md"),
+	}
+	for _, path := range created {
+		if err := fsys.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed := filepath.Join(testDir, "file.txt")
+	if err := fsys.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	noticed := make(map[string]bool)
+	for _, event := range pollOnce(w) {
+		if w.filtersOf(event.filters).notice(event) {
+			noticed[event.Path] = true
+		}
+	}
+	if !noticed[created[0]] || !noticed[created[2]] || noticed[created[1]] || noticed[removed] {
+		t.Errorf("expected events for %s and %s only, got %v", created[0], created[2], noticed)
+	}
+
+	// The options are kept in snapshots.
+	var buf bytes.Buffer
+	if err := w.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	restored.SetFileSystem(fsys)
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f := restored.fileTrees[testDir].filters
+	if f == nil || !f.ignoreHidden || len(f.ops) != 1 {
+		t.Errorf("expected the options of %s to be restored, got %+v", testDir, f)
+	}
+}
+
+func TestOverlappingRootOptions(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	inner := filepath.Join(testDir, "testDirTwo")
+
+	w := New()
+	w.SetFileSystem(fsys)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPathWithOptions(inner, Options{Recursive: true, Ops: []Op{Remove}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both trees find the new file, each event is filtered with the options of its own tree.
+	newFile := filepath.Join(inner, "new.txt")
+	if err := fsys.WriteFile(newFile, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var roots []string
+	for _, event := range pollOnce(w) {
+		if event.Path == newFile && w.filtersOf(event.filters).notice(event) {
+			roots = append(roots, event.Root)
+		}
+	}
+	if len(roots) != 1 || roots[0] != testDir {
+		t.Errorf("expected a create event from %s only, got %v", testDir, roots)
+	}
+}
+
+func TestOptionsMaxDepth(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	depth := 0
+	if err := w.AddPathWithOptions(testDir, Options{Recursive: true, MaxDepth: &depth}); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := w.RetrieveAllNodes(); len(nodes) != 1 {
+		t.Errorf("expected only %s to be watched, got %d nodes", testDir, len(nodes))
+	}
+}
@@ -0,0 +1,129 @@
+package gowatcher
+
+import (
+	"bytes"
+	"os"
+	"time"
+)
+
+// A ChangePolicy is a set of file attributes whose change is reported as an event.
+type ChangePolicy uint16
+
+// Change policies
+const (
+	ChangeModTime ChangePolicy = 1 << iota // A changed ModTime is a Write.
+	ChangeMode                             // A changed mode is a Chmod.
+	ChangeSize                             // A changed size is a Write.
+	ChangeCtime                            // A changed status change time is a Chmod.
+	ChangeInode                            // A new inode at the same path is a Replace.
+	ChangeNlink                            // A changed number of hard links is a Chmod.
+	ChangeUID                              // A changed owner is a Chown.
+	ChangeGID                              // A changed group is a Chown.
+	ChangeXattr                            // Changed extended attributes are an Xattr.
+
+	// DefaultChangePolicy only compares the ModTime and the mode.
+	DefaultChangePolicy = ChangeModTime | ChangeMode
+)
+
+// SetChangePolicy sets which file attributes are compared to detect a change.
+// Only ChangeModTime, ChangeMode and ChangeSize are supported on every platform,
+// the other attributes are only available on Linux and are ignored elsewhere.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) SetChangePolicy(policy ChangePolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changePolicy = policy
+}
+
+// fileStat holds the attributes of a file that os.FileInfo doesn't expose portably.
+type fileStat struct {
+	Dev   uint64
+	Ino   uint64
+	Nlink uint64
+	UID   uint32
+	GID   uint32
+	Ctime time.Time
+}
+
+// statOf returns the fileStat of the file info if the platform provides it.
+func statOf(info os.FileInfo) (*fileStat, bool) {
+	if info == nil {
+		return nil, false
+	}
+	if st, ok := info.Sys().(*fileStat); ok {
+		return st, true
+	}
+	return sysStat(info.Sys())
+}
+
+// ownerOf returns the owner of the file if the platform provides it.
+func ownerOf(info os.FileInfo) (Owner, bool) {
+	st, ok := statOf(info)
+	if !ok {
+		return Owner{}, false
+	}
+	return Owner{UID: int(st.UID), GID: int(st.GID)}, true
+}
+
+// fillNode reads the state of a node that isn't part of its os.FileInfo.
+func (w *GoWatcher) fillNode(node *FileNode) {
+	node.Owner, _ = ownerOf(node.Info)
+	node.Digest = w.digest(node.Path, node.Info)
+	node.Xattrs = w.xattrs(node.Path)
+}
+
+// changedEvents compares the old and current state of a node and returns the events
+// describing the change according to the gowatcher's change policy.
+func (w *GoWatcher) changedEvents(old, cur *FileNode) []Event {
+	p := w.changePolicy
+	oldStat, oldOK := statOf(old.Info)
+	curStat, curOK := statOf(cur.Info)
+	hasStat := oldOK && curOK
+	event := func(op Op) Event {
+		return Event{Op: op, Path: cur.Path, FileInfo: cur.Info, OldInfo: old.Info, Digest: cur.Digest}
+	}
+
+	// A followed symlink pointing to another target replaces the file at its path.
+	if old.Link != cur.Link {
+		return []Event{event(Replace)}
+	}
+
+	// A new inode at the same path means the file was atomically replaced,
+	// which makes any other difference irrelevant.
+	if p&ChangeInode != 0 && hasStat && (oldStat.Dev != curStat.Dev || oldStat.Ino != curStat.Ino) {
+		return []Event{event(Replace)}
+	}
+
+	var events []Event
+	written := p&ChangeModTime != 0 && !old.Info.ModTime().Equal(cur.Info.ModTime())
+	written = written || p&ChangeSize != 0 && old.Info.Size() != cur.Info.Size()
+	// When both the old and new content were hashed, the digests decide whether the file was written.
+	if old.Digest != nil && cur.Digest != nil {
+		written = !bytes.Equal(old.Digest, cur.Digest)
+	}
+	if written {
+		events = append(events, event(Write))
+	}
+
+	chmod := p&ChangeMode != 0 && old.Info.Mode() != cur.Info.Mode()
+	if hasStat {
+		chmod = chmod || p&ChangeCtime != 0 && !oldStat.Ctime.Equal(curStat.Ctime)
+		chmod = chmod || p&ChangeNlink != 0 && oldStat.Nlink != curStat.Nlink
+	}
+	if chmod {
+		events = append(events, event(Chmod))
+	}
+
+	if hasStat && (p&ChangeUID != 0 && oldStat.UID != curStat.UID || p&ChangeGID != 0 && oldStat.GID != curStat.GID) {
+		e := event(Chown)
+		e.OldOwner, e.NewOwner = old.Owner, cur.Owner
+		events = append(events, e)
+	}
+
+	if p&ChangeXattr != 0 && !equalXattrs(old.Xattrs, cur.Xattrs) {
+		e := event(Xattr)
+		e.OldXattrs, e.NewXattrs = old.Xattrs, cur.Xattrs
+		events = append(events, e)
+	}
+	return events
+}
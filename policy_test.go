@@ -0,0 +1,110 @@
+package gowatcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestChangePolicySize(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetChangePolicy(ChangeSize)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	file1 := filepath.Join(testDir, "file_1.txt")
+	if err := ioutil.WriteFile(fileTxt, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Only the ModTime of file_1.txt changes, which the policy doesn't look at.
+	if err := chtimes(file1, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var writes []Event
+	for _, event := range pollOnce(w) {
+		if event.Op == Write {
+			writes = append(writes, event)
+		}
+	}
+	if len(writes) != 1 || writes[0].Path != fileTxt {
+		t.Errorf("expected a single write event for %s, got %v", fileTxt, writes)
+	}
+}
+
+func TestChangePolicyReplace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetChangePolicy(DefaultChangePolicy | ChangeInode)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Atomically replace file.txt with a new file.
+	fileTxt := filepath.Join(testDir, "file.txt")
+	tmp := filepath.Join(testDir, "file.txt.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, fileTxt); err != nil {
+		t.Fatal(err)
+	}
+
+	var fileEvents []Event
+	for _, event := range pollOnce(w) {
+		if event.Path == fileTxt {
+			fileEvents = append(fileEvents, event)
+		}
+	}
+	if len(fileEvents) != 1 || fileEvents[0].Op != Replace {
+		t.Errorf("expected a single replace event for %s, got %v", fileTxt, fileEvents)
+	}
+}
+
+func TestChangePolicyChown(t *testing.T) {
+	// Changing the owner of a file requires root.
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		return
+	}
+
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetChangePolicy(DefaultChangePolicy | ChangeUID | ChangeGID)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	if err := os.Chown(fileTxt, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var chowns []Event
+	for _, event := range pollOnce(w) {
+		if event.Op == Chown {
+			chowns = append(chowns, event)
+		}
+	}
+	if len(chowns) != 1 {
+		t.Fatalf("expected 1 chown event, got %d", len(chowns))
+	}
+	if chowns[0].OldOwner != (Owner{UID: 0, GID: 0}) || chowns[0].NewOwner != (Owner{UID: 1, GID: 2}) {
+		t.Errorf("expected owner to change from 0:0 to 1:2, got %v to %v", chowns[0].OldOwner, chowns[0].NewOwner)
+	}
+}
@@ -0,0 +1,467 @@
+package gowatcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// treeInterval returns the file tree's own polling interval, or 0 if it's polled
+// on the interval passed to Start.
+func (w *GoWatcher) treeInterval(t *fileTree) time.Duration {
+	if t.interval > 0 {
+		return t.interval
+	}
+	if t.covered && w.backend != nil {
+		return w.reconcile
+	}
+	return 0
+}
+
+// resetDue makes every file tree due to be polled in the next cycle.
+func (w *GoWatcher) resetDue() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range w.fileTrees {
+		t.next = time.Time{}
+	}
+}
+
+// pollEvents polls every file tree for which due returns true, or every file tree if due is nil.
+// The events are sent once the file trees are unlocked.
+func (w *GoWatcher) pollEvents(evt chan Event, cancel chan struct{}, due func(t *fileTree) bool) {
+	w.mu.Lock()
+	c := w.newPollCycle(evt, cancel)
+	initial := w.queueInitial(c)
+
+	// Due trees are polled by priority, so that they are handed to the worker pool first.
+	var trees []*fileTree
+	for _, t := range w.fileTrees {
+		if due == nil || due(t) {
+			trees = append(trees, t)
+		}
+	}
+	sort.Slice(trees, func(i, j int) bool {
+		if trees[i].priority != trees[j].priority {
+			return trees[i].priority > trees[j].priority
+		}
+		return trees[i].path < trees[j].path
+	})
+
+	var wg sync.WaitGroup
+	for _, t := range trees {
+		t := t
+		c.run(&wg, func() {
+			w.pollRoot(c, t)
+		})
+	}
+	wg.Wait()
+	now := time.Now()
+	for _, t := range trees {
+		t.next = now.Add(w.treeInterval(t))
+	}
+	w.flush(c)
+	w.mu.Unlock()
+	w.deliver(c, initial)
+}
+
+// pollChanged polls the nodes of the paths reported by the backend. Only the direct
+// children of a directory are compared, its existing subdirectories aren't listed.
+func (w *GoWatcher) pollChanged(evt chan Event, cancel chan struct{}, paths []string) {
+	w.mu.Lock()
+	c := w.newPollCycle(evt, cancel)
+	c.shallow = true
+
+	for _, path := range paths {
+		t, node, parent := w.findNode(path)
+		if node == nil {
+			continue
+		}
+		if w.pollNodeEvent(c, t, node) != nil {
+			continue
+		}
+		// The node was removed, detach it from the tree.
+		if parent == nil {
+			t.root = nil
+			c.rootRemoved(t)
+			continue
+		}
+		parent.mu.Lock()
+		delete(parent.Children, filepath.Base(path))
+		parent.mu.Unlock()
+	}
+	w.flush(c)
+	w.mu.Unlock()
+	w.deliver(c, 0)
+}
+
+// deliver sends the events of a cycle once the file trees are unlocked, so that the consumers
+// can call the gowatcher while handling them. The first initial events are the queued Exists
+// and Ready events, the ones that weren't sent are kept for the next cycle.
+func (w *GoWatcher) deliver(c *pollCycle, initial int) {
+	sent := len(c.out)
+	for i, e := range c.out {
+		select {
+		case <-c.cancel:
+			sent = i
+		case c.evt <- e:
+			continue
+		}
+		break
+	}
+	if sent < initial {
+		w.mu.Lock()
+		w.initial = append(append([]Event(nil), c.out[sent:initial]...), w.initial...)
+		w.mu.Unlock()
+	}
+	c.out = nil
+}
+
+// nextDue returns when the earliest file tree with its own interval is due.
+// The trees covered by the backend are polled on the reconcile interval.
+func (w *GoWatcher) nextDue() (next time.Time, ok bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, t := range w.fileTrees {
+		if w.treeInterval(t) > 0 && (!ok || t.next.Before(next)) {
+			next, ok = t.next, true
+		}
+	}
+	return next, ok
+}
+
+// pollCycle holds the state shared by every pollNodeEvent call of one polling cycle.
+type pollCycle struct {
+	w       *GoWatcher
+	evt     chan Event
+	cancel  chan struct{}
+	workers chan struct{} // a token is held by every extra goroutine polling nodes
+	shallow bool          // only list the directories the cycle starts from
+
+	// mu protects the following.
+	// Create and Remove events are held back until the whole cycle has been polled,
+	// so that the removed and created nodes of a rename can be paired up.
+	mu         sync.Mutex
+	out        []Event  // events sent once the file trees are unlocked
+	dirs       []string // directories that aren't ignored anymore, added to the backend by flush
+	created    []Event
+	removed    []Event
+	rootErrors []error
+}
+
+func (w *GoWatcher) newPollCycle(evt chan Event, cancel chan struct{}) *pollCycle {
+	// The goroutine calling pollEvents is a worker too.
+	workers := 0
+	if w.concurrency > 1 {
+		workers = w.concurrency - 1
+	}
+	// The files that aren't ignored anymore are created along with the new ones.
+	created := w.unignored
+	w.unignored = nil
+	return &pollCycle{
+		w:       w,
+		evt:     evt,
+		cancel:  cancel,
+		workers: make(chan struct{}, workers),
+		created: created,
+	}
+}
+
+// run runs f on a new goroutine if the pool has a free worker, or on the calling goroutine otherwise.
+func (c *pollCycle) run(wg *sync.WaitGroup, f func()) {
+	wg.Add(1)
+	select {
+	case c.workers <- struct{}{}:
+		go func() {
+			defer func() {
+				<-c.workers
+				wg.Done()
+			}()
+			f()
+		}()
+	default:
+		f()
+		wg.Done()
+	}
+}
+
+func (c *pollCycle) cancelled() bool {
+	select {
+	case <-c.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// send queues an event, with the time it was detected if it isn't set yet. It returns false
+// if the cycle has been cancelled.
+func (c *pollCycle) send(e Event) bool {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out = append(c.out, e)
+	return !c.cancelled()
+}
+
+func (c *pollCycle) create(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, e)
+}
+
+// unignored adds a subtree that isn't ignored anymore to the cycle, with its Create events if they're announced.
+func (c *pollCycle) unignored(node *FileNode, events []Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, events...)
+	if node.Info.IsDir() {
+		c.dirs = append(c.dirs, node.Path)
+	}
+}
+
+func (c *pollCycle) remove(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removed = append(c.removed, e)
+}
+
+// flush pairs the held back events and queues them ordered by path. The directories that were
+// created or moved are added to the backend before, so that it notices any change made after the events.
+func (w *GoWatcher) flush(c *pollCycle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.Slice(c.removed, func(i, j int) bool { return c.removed[i].Path < c.removed[j].Path })
+	sort.Slice(c.created, func(i, j int) bool { return c.created[i].Path < c.created[j].Path })
+	events := pairMoves(c.removed, c.created, w.fsys.SameFile)
+	// The events of the trees with a higher priority are sent first.
+	sort.SliceStable(events, func(i, j int) bool { return w.priorityOf(events[i]) > w.priorityOf(events[j]) })
+	dirs := c.dirs
+	for _, e := range events {
+		if e.Op != Remove && e.IsDir() {
+			dirs = append(dirs, e.Path)
+		}
+	}
+	w.watchNewDirs(dirs)
+	c.out = append(c.out, events...)
+	c.removed, c.created, c.dirs = nil, nil, nil
+	w.rootErrors = append(w.rootErrors, c.rootErrors...)
+	c.rootErrors = nil
+}
+
+// priorityOf returns the priority of the file tree an event was found in.
+func (w *GoWatcher) priorityOf(e Event) int {
+	if t, found := w.fileTrees[e.Root]; found {
+		return t.priority
+	}
+	return 0
+}
+
+// pairMoves matches removed and created nodes by their file identity, their ModTime and size,
+// which a rename keeps, so that a reused inode isn't taken for a rename. Every matched pair is
+// turned into a Rename event if both paths share the same parent, or a Move event otherwise.
+// The Create events of the descendants of a moved directory are dropped.
+func pairMoves(removed, created []Event, sameFile func(fi1, fi2 os.FileInfo) bool) []Event {
+	if len(removed) == 0 || len(created) == 0 {
+		return append(removed, created...)
+	}
+	// The created nodes are bucketed, so that a removed node is only compared to the plausible ones.
+	buckets := make(map[moveKey][]int)
+	for i, cr := range created {
+		k := moveKeyOf(cr)
+		buckets[k] = append(buckets[k], i)
+	}
+	events := make([]Event, 0, len(removed)+len(created))
+	paired := make([]bool, len(created))
+	var movedDirs []string
+	for _, r := range removed {
+		k := moveKeyOf(r)
+		bucket := buckets[k]
+		found := false
+		for j, i := range bucket {
+			cr := created[i]
+			if !sameFile(r.FileInfo, cr.FileInfo) {
+				continue
+			}
+			buckets[k] = append(bucket[:j], bucket[j+1:]...)
+			paired[i], found = true, true
+			e := cr
+			e.Op, e.OldPath, e.OldInfo = Move, r.Path, r.FileInfo
+			if filepath.Dir(r.Path) == filepath.Dir(cr.Path) {
+				e.Op = Rename
+			}
+			events = append(events, e)
+			if cr.IsDir() {
+				movedDirs = append(movedDirs, cr.Path+string(filepath.Separator))
+			}
+			break
+		}
+		if !found {
+			events = append(events, r)
+		}
+	}
+outer:
+	for i, cr := range created {
+		if paired[i] {
+			continue
+		}
+		for _, dir := range movedDirs {
+			if strings.HasPrefix(cr.Path, dir) {
+				continue outer
+			}
+		}
+		events = append(events, cr)
+	}
+	return events
+}
+
+// moveKey holds what a rename keeps besides the file identity.
+type moveKey struct {
+	dir     bool
+	size    int64
+	modTime int64
+}
+
+func moveKeyOf(e Event) moveKey {
+	return moveKey{dir: e.IsDir(), size: e.Size(), modTime: e.ModTime().UnixNano()}
+}
+
+// To get every node's change and generate events.
+// A node is only ever polled by one goroutine, which owns its Children until it returns.
+func (w *GoWatcher) pollNodeEvent(c *pollCycle, t *fileTree, node *FileNode) *FileNode {
+	return w.pollNode(c, t, node, true)
+}
+
+// pollNode polls a node of the file tree t, its children are only listed if descend is true.
+func (w *GoWatcher) pollNode(c *pollCycle, t *fileTree, node *FileNode, descend bool) *FileNode {
+	if node == nil {
+		return nil
+	}
+	if c.cancelled() {
+		return node
+	}
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	// If the node was ignored, don't need to check it and just return
+	if node.ignored {
+		return node
+	}
+	// Check if the path was removed
+	newInfo, err := w.fsys.Lstat(node.Path)
+	if err != nil {
+		c.remove(t.event(Event{Op: Remove, Path: node.Path, FileInfo: node.Info, Digest: node.Digest}))
+		return nil
+	}
+	newInfo, link := w.resolve(node.Path, newInfo)
+	// Compare old info and new info
+	cur := &FileNode{Path: node.Path, Info: newInfo, Link: link}
+	w.fillNode(cur)
+	for _, e := range w.changedEvents(node, cur) {
+		if !c.send(t.event(e)) {
+			return node
+		}
+	}
+	node.Info, node.Link, node.Owner, node.Digest, node.Xattrs = cur.Info, cur.Link, cur.Owner, cur.Digest, cur.Xattrs
+
+	// If it's not a directory or its content isn't watched, just return.
+	if !newInfo.IsDir() || node.depth == 0 || !descend {
+		return node
+	}
+	// It's a directory, which was a file if it has no rules yet.
+	parentRules := w.rootRules(t.path, t.filters)
+	if node.parent != nil {
+		parentRules = node.parent.rules
+	}
+	if node.rules == nil {
+		node.rules = w.dirRules(parentRules, node.Path)
+	}
+	infoList, err := w.fsys.ReadDir(node.Path)
+	if err != nil {
+		return node
+	}
+	// The rules of a changed .gitignore file apply to the children before they're polled.
+	if w.gitignoreChanged(node, infoList) {
+		w.reapplyNode(c, t, node, parentRules, w.filtersOf(t.filters))
+	}
+	// Check new file list, the existing and new children are polled below.
+	present := make(map[string]bool, len(infoList))
+	var polled []*FileNode
+	var descended []bool
+	for _, info := range infoList {
+		name := info.Name()
+		path := filepath.Join(node.Path, name)
+
+		isHidden, err := w.fsys.IsHidden(path)
+		if err != nil {
+			return node
+		}
+
+		if w.filtersOf(node.rules.filterSet()).ignoreHidden && isHidden {
+			continue
+		}
+		present[name] = true
+		child, exist := node.Children[name]
+		if exist {
+			if child != nil && !child.ignored {
+				polled = append(polled, child)
+				descended = append(descended, !c.shallow)
+			}
+			continue
+		}
+		info, link := w.resolve(path, info)
+		ignored := w.ignored(node.rules, name, path, info.IsDir()) || w.followSymlinks && w.isCycle(node, info)
+		newChild := newNode(path, info, node.childDepth(), ignored)
+		newChild.Link, newChild.parent = link, node
+		node.Children[name] = newChild
+		if newChild.ignored {
+			continue
+		}
+		if info.IsDir() {
+			newChild.rules = w.dirRules(node.rules, path)
+		}
+		w.fillNode(newChild)
+		c.create(t.event(Event{Op: Create, Path: path, FileInfo: info, Digest: newChild.Digest}))
+		polled = append(polled, newChild)
+		descended = append(descended, true)
+	}
+	// Every child that isn't in the file list anymore was removed.
+	for name, child := range node.Children {
+		if present[name] {
+			continue
+		}
+		delete(node.Children, name)
+		if child == nil || child.ignored {
+			continue
+		}
+		c.remove(t.event(Event{Op: Remove, Path: child.Path, FileInfo: child.Info, Digest: child.Digest}))
+	}
+
+	// Directories are handed to the worker pool, files are polled right away.
+	results := make([]*FileNode, len(polled))
+	var wg sync.WaitGroup
+	for i, child := range polled {
+		i, child := i, child
+		if child.Info.IsDir() && descended[i] {
+			c.run(&wg, func() {
+				results[i] = w.pollNode(c, t, child, true)
+			})
+		} else {
+			results[i] = w.pollNode(c, t, child, descended[i])
+		}
+	}
+	wg.Wait()
+	for i, child := range polled {
+		name := filepath.Base(child.Path)
+		if results[i] == nil {
+			delete(node.Children, name)
+		} else {
+			node.Children[name] = results[i]
+		}
+	}
+	return node
+}
@@ -0,0 +1,126 @@
+package gowatcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPollConcurrency(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	for i := 0; i < 8; i++ {
+		dir := filepath.Join(testDir, fmt.Sprintf("dir_%d", i), "sub")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New()
+	w.SetConcurrency(4)
+	w.FilterOps(Create, Remove)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []string
+	for i := 0; i < 8; i++ {
+		path := filepath.Join(testDir, fmt.Sprintf("dir_%d", i), "sub", "new.txt")
+		if err := ioutil.WriteFile(path, []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, path)
+	}
+
+	// Creates are sent ordered by path, no matter which worker found them.
+	var created []string
+	for _, event := range pollOnce(w) {
+		if event.Op == Create {
+			created = append(created, event.Path)
+		}
+	}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("expected create events for %v, got %v", expected, created)
+	}
+
+	if len(w.RetrieveAllNodes()) != 8+3*8 {
+		t.Errorf("expected %d nodes, got %d", 8+3*8, len(w.RetrieveAllNodes()))
+	}
+}
+
+func TestPollEachNodeOnce(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	fileRecursive := filepath.Join(testDir, "testDirTwo", "file_recursive.txt")
+	if err := os.Chmod(fileRecursive, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for _, event := range pollOnce(w) {
+		if event.Path == fileRecursive {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected 1 event for %s, got %d", fileRecursive, count)
+	}
+}
+
+func TestAddPathWithDepth(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	dirTwo := filepath.Join(testDir, "testDirTwo")
+	deep := filepath.Join(dirTwo, "deep")
+	if err := fsys.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(filepath.Join(deep, "deep.txt"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.IgnoreHiddenFiles(true)
+	if err := w.AddPathWithDepth(testDir, 2); err != nil {
+		t.Fatal(err)
+	}
+	nodes := w.RetrieveAllNodes()
+	// The directory at the boundary is watched, but not its content.
+	if _, found := nodes[deep]; !found {
+		t.Errorf("expected %s to be watched", deep)
+	}
+	if _, found := nodes[filepath.Join(deep, "deep.txt")]; found {
+		t.Errorf("expected the content of %s not to be watched", deep)
+	}
+
+	// A directory created at the boundary is reported without being descended.
+	newDir := filepath.Join(dirTwo, "new")
+	if err := fsys.MkdirAll(filepath.Join(newDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(filepath.Join(deep, "new.txt"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	for _, event := range pollOnce(w) {
+		if event.Op == Create {
+			created = append(created, event.Path)
+		}
+	}
+	if len(created) != 1 || created[0] != newDir {
+		t.Errorf("expected a single create event for %s, got %v", newDir, created)
+	}
+	if _, found := w.RetrieveAllNodes()[filepath.Join(newDir, "sub")]; found {
+		t.Errorf("expected the content of %s not to be watched", newDir)
+	}
+}
@@ -0,0 +1,140 @@
+package gowatcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetPendingPaths makes AddPath and its variants accept paths that don't exist yet. Until a path
+// appears, only the nearest of its ancestors that exists is checked. Once it appears, Create events
+// are sent for the path and its content, even if its parent directories were created later.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) SetPendingPaths(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pendingPaths = enabled
+}
+
+// RootError is sent on the Error channel when a path added to the gowatcher is deleted. The path
+// stays watched, and its nodes are created again once it reappears.
+type RootError struct {
+	Path string
+	Err  error // ErrWatchedFileDeleted
+}
+
+func (e *RootError) Error() string {
+	return e.Err.Error() + ": " + e.Path
+}
+
+// Unwrap returns the underlying error, so that errors.Is(err, ErrWatchedFileDeleted) holds.
+func (e *RootError) Unwrap() error {
+	return e.Err
+}
+
+// pollRoot polls the root of a file tree, reporting its deletion or reattaching it once it reappears.
+func (w *GoWatcher) pollRoot(c *pollCycle, t *fileTree) {
+	if t.root == nil {
+		w.reattach(c, t)
+		return
+	}
+	if t.root = w.pollNodeEvent(c, t, t.root); t.root == nil {
+		c.rootRemoved(t)
+	}
+}
+
+// reattach traverses the root of a file tree again if it exists, and creates all of its nodes.
+func (w *GoWatcher) reattach(c *pollCycle, t *fileTree) {
+	if !w.appeared(t) {
+		return
+	}
+	root, err := w.traverseNode(t.path, t.maxDepth, w.rootRules(t.path, t.filters), nil)
+	if err != nil {
+		return
+	}
+	t.root = root
+	w.watchTree(t)
+
+	var create func(node *FileNode)
+	create = func(node *FileNode) {
+		if node == nil || node.ignored {
+			return
+		}
+		c.create(t.event(Event{Op: Create, Path: node.Path, FileInfo: node.Info, Digest: node.Digest}))
+		for _, child := range node.Children {
+			create(child)
+		}
+	}
+	create(root)
+}
+
+// appeared reports whether the missing root of a file tree exists again. The path is checked from
+// the nearest existing ancestor found by the previous call, which is updated if it changed.
+func (w *GoWatcher) appeared(t *fileTree) bool {
+	dir := t.ancestor
+	if dir == "" {
+		dir = filepath.Dir(t.path)
+	}
+	// The ancestor may have been removed too, find the nearest one that exists.
+	for {
+		if info, err := w.fsys.Lstat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+
+	// Walk down to the root as long as the path exists.
+	rel, err := filepath.Rel(dir, t.path)
+	if err != nil || outside(rel) {
+		return false
+	}
+	t.ancestor = dir
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		path := filepath.Join(t.ancestor, name)
+		info, err := w.fsys.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 && !w.followSymlinks {
+			return false
+		}
+		if path == t.path {
+			t.ancestor = ""
+			return true
+		}
+		if !info.IsDir() {
+			return false
+		}
+		t.ancestor = path
+	}
+	return false
+}
+
+// rootRemoved records the deletion of the root of a file tree, which is polled again until it reappears.
+func (c *pollCycle) rootRemoved(t *fileTree) {
+	t.covered = false
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rootErrors = append(c.rootErrors, &RootError{Path: t.path, Err: ErrWatchedFileDeleted})
+}
+
+// sendRootErrors sends the errors of the deleted roots on the Error channel.
+func (w *GoWatcher) sendRootErrors(ctx context.Context) {
+	w.mu.Lock()
+	errs := w.rootErrors
+	w.rootErrors = nil
+	w.mu.Unlock()
+	for i, err := range errs {
+		select {
+		case <-ctx.Done():
+			// Keep the errors that weren't sent for the next run.
+			w.mu.Lock()
+			w.rootErrors = append(errs[i:], w.rootErrors...)
+			w.mu.Unlock()
+			return
+		case w.Error <- err:
+		}
+	}
+}
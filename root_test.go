@@ -0,0 +1,169 @@
+package gowatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRootReattach(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(testDir); err != nil {
+		t.Fatal(err)
+	}
+
+	events := pollOnce(w)
+	if len(events) != 1 || events[0].Op != Remove || events[0].Path != testDir {
+		t.Errorf("expected a single remove event for %s, got %v", testDir, events)
+	}
+	if len(w.rootErrors) != 1 {
+		t.Fatalf("expected a root error, got %v", w.rootErrors)
+	}
+	if err, ok := w.rootErrors[0].(*RootError); !ok || err.Path != testDir || err.Unwrap() != ErrWatchedFileDeleted {
+		t.Errorf("expected the root error of %s, got %v", testDir, w.rootErrors[0])
+	}
+	w.rootErrors = nil
+
+	// The root stays registered while it doesn't exist.
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+	if len(w.fileTrees) != 1 {
+		t.Errorf("expected the root to stay registered")
+	}
+
+	newFile := filepath.Join(testDir, "sub", "new.txt")
+	if err := os.MkdirAll(filepath.Dir(newFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{testDir, filepath.Dir(newFile), newFile}
+	events = pollOnce(w)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d create events, got %v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Op != Create || event.Path != expected[i] {
+			t.Errorf("expected a create event for %s, got %v", expected[i], event)
+		}
+	}
+	if len(w.rootErrors) != 0 {
+		t.Errorf("expected no root error, got %v", w.rootErrors)
+	}
+}
+
+func TestRootErrorSent(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, 10*time.Millisecond)
+	}()
+	w.Wait()
+	go func() {
+		for range w.Event {
+		}
+	}()
+
+	if err := os.RemoveAll(testDir); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-w.Error:
+		if rootErr, ok := err.(*RootError); !ok || rootErr.Path != testDir {
+			t.Errorf("expected the root error of %s, got %v", testDir, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("received no error")
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected error to be %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestPendingPath(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	target := filepath.Join(testDir, "a", "b", "c")
+	if err := w.AddPath(target, true); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	w.SetPendingPaths(true)
+	if err := w.AddPath(target, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+	// The intermediate directories aren't reported, only checked.
+	if err := fsys.MkdirAll(filepath.Join(testDir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+	if ancestor := w.fileTrees[target].ancestor; ancestor != filepath.Join(testDir, "a", "b") {
+		t.Errorf("expected the nearest ancestor to be %s, got %s", filepath.Join(testDir, "a", "b"), ancestor)
+	}
+
+	newFile := filepath.Join(target, "new.txt")
+	if err := fsys.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(newFile, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{target, newFile}
+	events := pollOnce(w)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d create events, got %v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Op != Create || event.Path != expected[i] {
+			t.Errorf("expected a create event for %s, got %v", expected[i], event)
+		}
+	}
+}
+
+func TestPendingPathDotDotName(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.SetPendingPaths(true)
+	// A name starting with .. is inside its parent, like the ..data directories of Kubernetes.
+	target := filepath.Join(testDir, "..data")
+	if err := w.AddPath(target, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	events := pollOnce(w)
+	if len(events) != 1 || events[0].Op != Create || events[0].Path != target {
+		t.Errorf("expected a create event for %s, got %v", target, events)
+	}
+}
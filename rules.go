@@ -0,0 +1,117 @@
+package gowatcher
+
+import (
+	"path/filepath"
+)
+
+// SetAnnounceUnignored makes the gowatcher send Create events for the files and directories that
+// aren't ignored anymore after the ignore rules changed. By default they're watched silently.
+func (w *GoWatcher) SetAnnounceUnignored(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.announceUnignored = enabled
+}
+
+// ClearIgnores removes every rule set with IgnoreName, IgnorePath, IgnorePatterns, IncludeGlob
+// and ExcludeGlob. The files they ignored are scanned by the next polling cycle.
+func (w *GoWatcher) ClearIgnores() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
+	w.nameIgnores, w.pathIgnores, w.ignorePatterns = nil, nil, nil
+	w.includeGlobs, w.excludeGlobs = nil, nil
+}
+
+// reapplyRules applies changed ignore rules to the existing file trees, old is the gowatcher's
+// filter set before the change. The nodes that are ignored now are pruned without any event, and
+// the ones that aren't ignored anymore are scanned, and announced if announceUnignored is set.
+// The files that weren't ignored by the old rules and aren't in the trees yet are new, they're
+// left to the polling cycle.
+func (w *GoWatcher) reapplyRules(old filterSet) {
+	for _, t := range w.fileTrees {
+		if t.root == nil || t.root.ignored {
+			continue
+		}
+		oldFilters := &old
+		if t.filters != nil {
+			oldFilters = t.filters
+		}
+		w.reapplyNode(nil, t, t.root, w.rootRules(t.path, t.filters), oldFilters)
+	}
+}
+
+// reapplyNode applies the ignore rules to the children of a directory, rules are the new rules applying to it.
+// The polling cycle is nil unless the rules of a changed .gitignore file are applied while polling the directory.
+func (w *GoWatcher) reapplyNode(c *pollCycle, t *fileTree, node *FileNode, rules *ignoreRules, old *filterSet) {
+	if !node.Info.IsDir() || node.depth == 0 {
+		return
+	}
+	oldRules := node.rules
+	node.rules = w.dirRules(rules, node.Path)
+	cur := w.filtersOf(t.filters)
+	wasIgnored := func(name, path string, isDir, isHidden bool) bool {
+		return old.ignored(oldRules, name, path, isDir) || old.ignoreHidden && isHidden
+	}
+
+	for name, child := range node.Children {
+		if child == nil {
+			continue
+		}
+		isHidden, _ := w.fsys.IsHidden(child.Path)
+		isDir := child.Info.IsDir()
+		ignored := cur.ignored(node.rules, name, child.Path, isDir) || cur.ignoreHidden && isHidden
+		switch {
+		case !child.ignored && ignored:
+			// The polling cycle adds it back as an ignored node if it's not hidden.
+			delete(node.Children, name)
+		case !child.ignored:
+			w.reapplyNode(c, t, child, node.rules, old)
+		case !ignored && wasIgnored(name, child.Path, isDir, isHidden):
+			// A node ignored because it's a symlink or a cycle stays ignored.
+			w.unignore(c, t, node, name)
+		}
+	}
+
+	infoList, err := w.fsys.ReadDir(node.Path)
+	if err != nil {
+		return
+	}
+	for _, info := range infoList {
+		name := info.Name()
+		if _, found := node.Children[name]; found {
+			continue
+		}
+		path := filepath.Join(node.Path, name)
+		isHidden, _ := w.fsys.IsHidden(path)
+		ignored := cur.ignored(node.rules, name, path, info.IsDir()) || cur.ignoreHidden && isHidden
+		if !ignored && wasIgnored(name, path, info.IsDir(), isHidden) {
+			w.unignore(c, t, node, name)
+		}
+	}
+}
+
+// unignore scans a child of a directory that isn't ignored anymore. While polling, the child is added
+// to the cycle, otherwise its events are sent by the next cycle.
+func (w *GoWatcher) unignore(c *pollCycle, t *fileTree, node *FileNode, name string) {
+	child, err := w.traverseNode(filepath.Join(node.Path, name), node.childDepth(), node.rules, node)
+	if err != nil || child.ignored {
+		return
+	}
+	node.Children[name] = child
+	var events []Event
+	if w.announceUnignored {
+		for _, n := range child.RetrieveAllNodes() {
+			if !n.ignored {
+				events = append(events, t.event(Event{Op: Create, Path: n.Path, FileInfo: n.Info, Digest: n.Digest}))
+			}
+		}
+	}
+	if c != nil {
+		c.unignored(child, events)
+		return
+	}
+	if t.covered {
+		t.covered = w.watchNode(child)
+	}
+	w.unignored = append(w.unignored, events...)
+}
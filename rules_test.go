@@ -0,0 +1,67 @@
+package gowatcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReapplyRules(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	dirTwo := filepath.Join(testDir, "testDirTwo")
+	recursive := filepath.Join(dirTwo, "file_recursive.txt")
+	dotfile := filepath.Join(testDir, ".dotfile")
+	file1 := filepath.Join(testDir, "file_1.txt")
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.IgnoreHiddenFiles(true)
+	w.IgnoreName(`^testDirTwo$`)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if events := pollOnce(w); len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+
+	// A newly ignored file is pruned without a Remove event.
+	w.IgnoreName(`^file_1\.txt$`)
+	if node, found := w.RetrieveAllNodes()[file1]; found && !node.ignored {
+		t.Errorf("expected %s to be pruned", file1)
+	}
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	// A newly un-ignored file is announced if enabled.
+	w.SetAnnounceUnignored(true)
+	w.IgnoreHiddenFiles(false)
+	if node, found := w.RetrieveAllNodes()[dotfile]; !found || node.ignored {
+		t.Errorf("expected %s to be watched", dotfile)
+	}
+	events := pollOnce(w)
+	if len(events) != 1 || events[0].Op != Create || events[0].Path != dotfile {
+		t.Errorf("expected a create event for %s, got %v", dotfile, events)
+	}
+
+	// Otherwise the un-ignored subtrees are scanned silently.
+	w.SetAnnounceUnignored(false)
+	w.ClearIgnores()
+	nodes := w.RetrieveAllNodes()
+	for _, path := range []string{file1, dirTwo, recursive} {
+		if node, found := nodes[path]; !found || node.ignored {
+			t.Errorf("expected %s to be watched", path)
+		}
+	}
+	if events := pollOnce(w); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	// The un-ignored subtrees are polled like the others.
+	if err := fsys.Remove(recursive); err != nil {
+		t.Fatal(err)
+	}
+	events = pollOnce(w)
+	if len(events) == 0 || events[len(events)-1].Op != Remove || events[len(events)-1].Path != recursive {
+		t.Errorf("expected a remove event for %s, got %v", recursive, events)
+	}
+}
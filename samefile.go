@@ -5,5 +5,11 @@ package gowatcher
 import "os"
 
 func sameFile(fi1, fi2 os.FileInfo) bool {
-	return os.SameFile(fi1, fi2)
+	if os.SameFile(fi1, fi2) {
+		return true
+	}
+	// The file info restored from a snapshot only has a fileStat.
+	st1, ok1 := statOf(fi1)
+	st2, ok2 := statOf(fi2)
+	return ok1 && ok2 && st1.Dev == st2.Dev && st1.Ino == st2.Ino
 }
@@ -0,0 +1,64 @@
+package gowatcher
+
+import "time"
+
+// AdaptiveInterval configures how the polling interval adapts to the activity of the watched files.
+// After a cycle that found events the interval drops to Min, every quiet cycle multiplies it by Backoff
+// until it reaches Max. The interval never falls below ScanFactor times the duration of the last scan.
+type AdaptiveInterval struct {
+	Min        time.Duration // Interval after activity, the duration passed to Start if less than 1ns.
+	Max        time.Duration // Ceiling of the backoff, Min if less than Min.
+	Backoff    float64       // Multiplier applied after a quiet cycle, 2 if not greater than 1.
+	ScanFactor float64       // Multiple of the last scan duration, 1 if not greater than 0.
+}
+
+// SetAdaptiveInterval makes the gowatcher adapt its polling interval to the activity
+// instead of always sleeping the duration passed to Start.
+func (w *GoWatcher) SetAdaptiveInterval(a AdaptiveInterval) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.adaptive = &a
+}
+
+// scheduler computes how long to sleep between two polling cycles.
+type scheduler struct {
+	interval time.Duration
+	adaptive *AdaptiveInterval
+}
+
+func newScheduler(d time.Duration, adaptive *AdaptiveInterval) *scheduler {
+	if adaptive == nil {
+		return &scheduler{interval: d}
+	}
+	a := *adaptive
+	if a.Min < time.Nanosecond {
+		a.Min = d
+	}
+	if a.Max < a.Min {
+		a.Max = a.Min
+	}
+	if a.Backoff <= 1 {
+		a.Backoff = 2
+	}
+	if a.ScanFactor <= 0 {
+		a.ScanFactor = 1
+	}
+	return &scheduler{interval: a.Min, adaptive: &a}
+}
+
+// next returns the sleep after a cycle which found events if active is true and took scan to run.
+func (s *scheduler) next(active bool, scan time.Duration) time.Duration {
+	a := s.adaptive
+	if a == nil {
+		return s.interval
+	}
+	if active {
+		s.interval = a.Min
+	} else if s.interval = time.Duration(float64(s.interval) * a.Backoff); s.interval > a.Max {
+		s.interval = a.Max
+	}
+	if floor := time.Duration(float64(scan) * a.ScanFactor); s.interval < floor {
+		return floor
+	}
+	return s.interval
+}
@@ -0,0 +1,40 @@
+package gowatcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulerFixed(t *testing.T) {
+	s := newScheduler(time.Millisecond*100, nil)
+
+	if d := s.next(false, time.Second); d != time.Millisecond*100 {
+		t.Errorf("expected a fixed interval of 100ms, got %s", d)
+	}
+}
+
+func TestSchedulerAdaptive(t *testing.T) {
+	s := newScheduler(time.Millisecond*100, &AdaptiveInterval{Max: time.Millisecond * 500})
+
+	testCases := []struct {
+		active   bool
+		scan     time.Duration
+		expected time.Duration
+	}{
+		{false, 0, time.Millisecond * 200},
+		{false, 0, time.Millisecond * 400},
+		{false, 0, time.Millisecond * 500},
+		{false, 0, time.Millisecond * 500},
+		{true, 0, time.Millisecond * 100},
+		// The sleep never falls below the scan duration.
+		{true, time.Millisecond * 300, time.Millisecond * 300},
+		{false, time.Millisecond * 300, time.Millisecond * 300},
+		{false, 0, time.Millisecond * 400},
+	}
+
+	for i, tc := range testCases {
+		if d := s.next(tc.active, tc.scan); d != tc.expected {
+			t.Errorf("%d: expected %s, got %s", i, tc.expected, d)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package gowatcher
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is the version of the format written by SaveSnapshot.
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int             `json:"version"`
+	Trees   []*snapshotTree `json:"trees"`
+}
+
+type snapshotTree struct {
+	Path     string        `json:"path"`
+	MaxDepth int           `json:"maxDepth"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Priority int           `json:"priority,omitempty"`
+	Options  *Options      `json:"options,omitempty"`
+	Root     *snapshotNode `json:"root,omitempty"`
+}
+
+type snapshotNode struct {
+	Name     string            `json:"name"`
+	Size     int64             `json:"size"`
+	Mode     os.FileMode       `json:"mode"`
+	ModTime  time.Time         `json:"modTime"`
+	Stat     *fileStat         `json:"stat,omitempty"`
+	Link     string            `json:"link,omitempty"`
+	Digest   []byte            `json:"digest,omitempty"`
+	Xattrs   map[string][]byte `json:"xattrs,omitempty"`
+	Ignored  bool              `json:"ignored,omitempty"`
+	Depth    int               `json:"depth"`
+	Children []*snapshotNode   `json:"children,omitempty"`
+}
+
+// SaveSnapshot writes the file trees to w in a versioned format, so that they
+// can be restored with LoadSnapshot after the process restarted.
+func (w *GoWatcher) SaveSnapshot(wr io.Writer) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	s := snapshot{Version: snapshotVersion}
+	for _, t := range w.fileTrees {
+		s.Trees = append(s.Trees, &snapshotTree{
+			Path:     t.path,
+			MaxDepth: t.maxDepth,
+			Interval: t.interval,
+			Priority: t.priority,
+			Options:  t.opts,
+			Root:     newSnapshotNode(t.root),
+		})
+	}
+	sort.Slice(s.Trees, func(i, j int) bool { return s.Trees[i].Path < s.Trees[j].Path })
+	return json.NewEncoder(wr).Encode(s)
+}
+
+// LoadSnapshot restores the file trees written by SaveSnapshot, replacing the trees of the
+// same paths. The first polling cycle then sends the events of every change made since
+// the snapshot was saved.
+func (w *GoWatcher) LoadSnapshot(r io.Reader) error {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	filters := make([]*filterSet, len(s.Trees))
+	for i, st := range s.Trees {
+		if st.Options == nil {
+			continue
+		}
+		f, err := st.Options.compile()
+		if err != nil {
+			return err
+		}
+		filters[i] = f
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, st := range s.Trees {
+		if old, found := w.fileTrees[st.Path]; found {
+			w.unwatchTree(old)
+		}
+		t := &fileTree{
+			path:     st.Path,
+			root:     st.Root.fileNode(st.Path),
+			maxDepth: st.MaxDepth,
+			interval: st.Interval,
+			priority: st.Priority,
+			filters:  filters[i],
+			opts:     st.Options,
+		}
+		w.attachRules(t.root, w.rootRules(st.Path, t.filters))
+		w.fileTrees[st.Path] = t
+		w.watchTree(t)
+	}
+	return nil
+}
+
+func newSnapshotNode(node *FileNode) *snapshotNode {
+	if node == nil {
+		return nil
+	}
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+	sn := &snapshotNode{
+		Name:    node.Info.Name(),
+		Size:    node.Info.Size(),
+		Mode:    node.Info.Mode(),
+		ModTime: node.Info.ModTime(),
+		Link:    node.Link,
+		Digest:  node.Digest,
+		Xattrs:  node.Xattrs,
+		Ignored: node.ignored,
+		Depth:   node.depth,
+	}
+	sn.Stat, _ = statOf(node.Info)
+	for _, child := range node.Children {
+		if child != nil {
+			sn.Children = append(sn.Children, newSnapshotNode(child))
+		}
+	}
+	sort.Slice(sn.Children, func(i, j int) bool { return sn.Children[i].Name < sn.Children[j].Name })
+	return sn
+}
+
+// fileNode converts the snapshot node back to the FileNode of the path.
+func (sn *snapshotNode) fileNode(path string) *FileNode {
+	if sn == nil {
+		return nil
+	}
+	info := &fileInfo{
+		name:    sn.Name,
+		size:    sn.Size,
+		mode:    sn.Mode,
+		modTime: sn.ModTime,
+		dir:     sn.Mode.IsDir(),
+	}
+	// A nil *fileStat must not be stored in the interface.
+	if sn.Stat != nil {
+		info.sys = sn.Stat
+	}
+	node := newNode(path, info, sn.Depth, sn.Ignored)
+	node.Owner, _ = ownerOf(info)
+	node.Link = sn.Link
+	node.Digest = sn.Digest
+	node.Xattrs = sn.Xattrs
+	for _, child := range sn.Children {
+		c := child.fileNode(filepath.Join(path, child.Name))
+		c.parent = node
+		node.Children[child.Name] = c
+	}
+	return node
+}
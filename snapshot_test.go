@@ -0,0 +1,80 @@
+package gowatcher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := w.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the tree while no watcher is running.
+	fileTxt := filepath.Join(testDir, "file.txt")
+	file1 := filepath.Join(testDir, "file_1.txt")
+	newFile := filepath.Join(testDir, "testDirTwo", "new.txt")
+	if err := chtimes(fileTxt, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// Create before removing, so that the inode of file_1.txt can't be reused.
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(file1); err != nil {
+		t.Fatal(err)
+	}
+
+	w = New()
+	if err := w.LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.RetrieveAllNodes()) == 0 {
+		t.Fatal("expected the snapshot to restore the file trees")
+	}
+
+	expected := map[string]Op{
+		fileTxt: Write,
+		file1:   Remove,
+		newFile: Create,
+	}
+	for _, event := range pollOnce(w) {
+		if event.IsDir() {
+			continue
+		}
+		if op, found := expected[event.Path]; !found || op != event.Op {
+			t.Errorf("unexpected event %v", event)
+			continue
+		}
+		delete(expected, event.Path)
+	}
+	if len(expected) != 0 {
+		t.Errorf("missing events %v", expected)
+	}
+
+	// The restored tree is now up to date.
+	for _, event := range pollOnce(w) {
+		t.Errorf("unexpected event %v", event)
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	w := New()
+	err := w.LoadSnapshot(strings.NewReader(`{"version":0,"trees":[]}`))
+	if err != ErrSnapshotVersion {
+		t.Errorf("expected error to be %s, got %v", ErrSnapshotVersion, err)
+	}
+}
@@ -0,0 +1,23 @@
+// +build linux
+
+package gowatcher
+
+import (
+	"syscall"
+	"time"
+)
+
+func sysStat(sys interface{}) (*fileStat, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return nil, false
+	}
+	return &fileStat{
+		Dev:   uint64(st.Dev),
+		Ino:   uint64(st.Ino),
+		Nlink: uint64(st.Nlink),
+		UID:   st.Uid,
+		GID:   st.Gid,
+		Ctime: time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec)),
+	}, true
+}
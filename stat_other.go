@@ -0,0 +1,7 @@
+// +build !linux
+
+package gowatcher
+
+func sysStat(sys interface{}) (*fileStat, bool) {
+	return nil, false
+}
@@ -0,0 +1,160 @@
+package gowatcher
+
+import (
+	"context"
+	"regexp"
+	"sync"
+)
+
+// DefaultSubscriptionBuffer is the capacity of a subscription's channel when none is set.
+const DefaultSubscriptionBuffer = 64
+
+// SubscribeOptions selects the events received by a subscription.
+type SubscribeOptions struct {
+	Buffer int      // Capacity of the Events channel, DefaultSubscriptionBuffer if less than 1.
+	Ops    []Op     // Only receive these ops, every op if empty.
+	Names  []string // Only receive the events whose name matches one of these regexes, or one of Paths.
+	Paths  []string // Only receive the events whose path matches one of these regexes, or one of Names.
+}
+
+// Subscription receives the events of a gowatcher independently of the other subscriptions
+// and of the Event channel. A full Events channel holds back the polling cycle, like Event does.
+type Subscription struct {
+	Events <-chan Event
+
+	w           *GoWatcher
+	events      chan Event
+	ops         map[Op]struct{}
+	nameFilters []*regexp.Regexp
+	pathFilters []*regexp.Regexp
+	done        chan struct{} // closed by Unsubscribe
+	once        sync.Once
+
+	// mu is held while sending, so that Events is only closed once no event is being sent.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Subscribe creates a subscription to the events found by the polling cycles. The filters of the
+// gowatcher, its max events, debouncing and batch mode only apply to the Event and Batches channels.
+// It returns an error if a regex of the options can't be compiled.
+func (w *GoWatcher) Subscribe(opts SubscribeOptions) (*Subscription, error) {
+	nameFilters, err := compileRegexps(opts.Names)
+	if err != nil {
+		return nil, err
+	}
+	pathFilters, err := compileRegexps(opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Buffer < 1 {
+		opts.Buffer = DefaultSubscriptionBuffer
+	}
+	s := &Subscription{
+		w:           w,
+		events:      make(chan Event, opts.Buffer),
+		nameFilters: nameFilters,
+		pathFilters: pathFilters,
+		done:        make(chan struct{}),
+	}
+	s.Events = s.events
+	if len(opts.Ops) > 0 {
+		s.ops = make(map[Op]struct{})
+		for _, op := range opts.Ops {
+			s.ops[op] = struct{}{}
+		}
+	}
+
+	w.subMu.Lock()
+	defer w.subMu.Unlock()
+	w.subs = append(w.subs, s)
+	return s, nil
+}
+
+// Unsubscribe stops the subscription and closes its Events channel.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		w := s.w
+		// publish keeps iterating over the old slice, so a new one is built.
+		w.subMu.Lock()
+		subs := make([]*Subscription, 0, len(w.subs))
+		for _, sub := range w.subs {
+			if sub != s {
+				subs = append(subs, sub)
+			}
+		}
+		w.subs = subs
+		w.subMu.Unlock()
+
+		// Unblock a pending send before closing the channel.
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.events)
+		s.mu.Unlock()
+	})
+}
+
+func (s *Subscription) match(e Event) bool {
+	if s.ops != nil {
+		if _, found := s.ops[e.Op]; !found {
+			return false
+		}
+	}
+	if len(s.nameFilters) == 0 && len(s.pathFilters) == 0 {
+		return true
+	}
+	for _, reg := range s.nameFilters {
+		if reg.MatchString(e.Name()) {
+			return true
+		}
+	}
+	for _, reg := range s.pathFilters {
+		if reg.MatchString(e.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends an event matching the subscription, it returns false if the context was cancelled first.
+func (s *Subscription) send(ctx context.Context, e Event) bool {
+	if !s.match(e) {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.done:
+		return true
+	case s.events <- e:
+		return true
+	}
+}
+
+// publish sends an event to every subscription, it returns false if the context was cancelled first.
+func (w *GoWatcher) publish(ctx context.Context, e Event) bool {
+	w.subMu.RLock()
+	subs := w.subs
+	w.subMu.RUnlock()
+	for _, s := range subs {
+		if !s.send(ctx, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetEventChannel controls whether the events are sent on the Event and Batches channels, which is
+// the default. Disable it when the events are only received through subscriptions.
+// Notice: This function should be called before Start.
+func (w *GoWatcher) SetEventChannel(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.noEventChannel = !enabled
+}
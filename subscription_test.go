@@ -0,0 +1,138 @@
+package gowatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, s *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-s.Events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("received no event")
+	}
+	return Event{}
+}
+
+func subscribe(t *testing.T, w *GoWatcher, opts SubscribeOptions) *Subscription {
+	t.Helper()
+	s, err := w.Subscribe(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSubscribe(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.SetEventChannel(false)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	creates := subscribe(t, w, SubscribeOptions{Ops: []Op{Create}, Names: []string{`\.txt$`}})
+	removes := subscribe(t, w, SubscribeOptions{Ops: []Op{Remove}})
+	unsubscribed := subscribe(t, w, SubscribeOptions{Buffer: 1})
+	if _, err := w.Subscribe(SubscribeOptions{Paths: []string{"[a"}}); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+	unsubscribed.Unsubscribe()
+	if _, ok := <-unsubscribed.Events; ok {
+		t.Error("expected the channel to be closed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, 10*time.Millisecond)
+	}()
+	w.Wait()
+
+	newFile := filepath.Join(testDir, "new.txt")
+	if err := ioutil.WriteFile(filepath.Join(testDir, "new.md"), []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if event := receive(t, creates); event.Op != Create || event.Path != newFile {
+		t.Errorf("expected a create event for %s, got %v", newFile, event)
+	}
+
+	if err := os.Remove(newFile); err != nil {
+		t.Fatal(err)
+	}
+	// Both subscriptions share the scan, the removal is only received by the second one.
+	if event := receive(t, removes); event.Op != Remove || event.Path != newFile {
+		t.Errorf("expected a remove event for %s, got %v", newFile, event)
+	}
+	select {
+	case event := <-creates.Events:
+		t.Errorf("unexpected event %v", event)
+	default:
+	}
+
+	// A subscription that isn't read anymore doesn't hold back the others once it's stopped.
+	creates.Unsubscribe()
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Remove(newFile); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if event := receive(t, removes); event.Path == newFile {
+			break
+		}
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected error to be %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestUnsubscribeWhilePublishing(t *testing.T) {
+	w := New()
+	const n = 200
+	var kept, stopped []*Subscription
+	for i := 0; i < 50; i++ {
+		stopped = append(stopped, subscribe(t, w, SubscribeOptions{Buffer: 2 * n}))
+		kept = append(kept, subscribe(t, w, SubscribeOptions{Buffer: 2 * n}))
+	}
+
+	// The buffers are large enough for a subscription receiving an event twice.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			w.publish(context.Background(), Event{Op: Create, Path: "-", FileInfo: &fileInfo{name: "-"}})
+		}
+	}()
+	for _, s := range stopped {
+		s.Unsubscribe()
+		time.Sleep(time.Millisecond)
+	}
+	<-done
+
+	// The subscriptions still active receive every event exactly once.
+	for _, s := range kept {
+		s.Unsubscribe()
+		received := 0
+		for range s.Events {
+			received++
+		}
+		if received != n {
+			t.Errorf("expected %d events, got %d", n, received)
+		}
+	}
+}
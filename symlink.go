@@ -0,0 +1,49 @@
+package gowatcher
+
+import "os"
+
+// FollowSymlinks makes the gowatcher follow the symlinks it finds, including the added paths, instead
+// of ignoring them. The nodes of a symlink describe its target, and a Replace event is sent when
+// the symlink points to another target. A directory that is one of its own parents through a symlink isn't followed.
+// The FileSystem must implement SymlinkFileSystem, which OSFileSystem does.
+// Notice: This function should be called before adding paths.
+func (w *GoWatcher) FollowSymlinks(follow bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.followSymlinks = follow
+}
+
+// resolve returns the os.FileInfo of the target of a symlink that is followed, and the target.
+// Any other os.FileInfo is returned as is, and so is the one of a broken symlink, which is ignored.
+func (w *GoWatcher) resolve(path string, info os.FileInfo) (os.FileInfo, string) {
+	if !w.followSymlinks || info.Mode()&os.ModeSymlink == 0 {
+		return info, ""
+	}
+	fsys, ok := w.fsys.(SymlinkFileSystem)
+	if !ok {
+		return info, ""
+	}
+	link, err := fsys.Readlink(path)
+	if err != nil {
+		return info, ""
+	}
+	target, err := fsys.Stat(path)
+	if err != nil {
+		return info, link
+	}
+	return target, link
+}
+
+// isCycle reports whether a directory is the one of the parent node or of one of its ancestors,
+// which happens when it's reached through a followed symlink.
+func (w *GoWatcher) isCycle(parent *FileNode, info os.FileInfo) bool {
+	if !info.IsDir() {
+		return false
+	}
+	for node := parent; node != nil; node = node.parent {
+		if w.fsys.SameFile(node.Info, info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package gowatcher
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFollowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	// Watch testDir/watched, which links to testDirTwo, and to the watched directory itself.
+	watched := filepath.Join(testDir, "watched")
+	if err := os.Mkdir(watched, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(watched, "link")
+	loop := filepath.Join(watched, "loop")
+	if err := os.Symlink(filepath.Join(testDir, "testDirTwo"), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(watched, loop); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	if err := w.AddPath(link, true); err != ErrWatchSymlink {
+		t.Errorf("expected error to be %s, got %v", ErrWatchSymlink, err)
+	}
+	w.FollowSymlinks(true)
+	if err := w.AddPath(watched, true); err != nil {
+		t.Fatal(err)
+	}
+
+	linked := filepath.Join(link, "file_recursive.txt")
+	nodes := w.RetrieveAllNodes()
+	if node, found := nodes[linked]; !found || node.ignored {
+		t.Errorf("expected %s to be watched through the symlink", linked)
+	}
+	if node := nodes[loop]; !node.ignored || len(node.Children) != 0 {
+		t.Errorf("expected the cycle through %s not to be followed", loop)
+	}
+
+	// A change of the target is seen through the symlink.
+	if err := chtimes(filepath.Join(testDir, "testDirTwo", "file_recursive.txt"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	events := pollOnce(w)
+	if len(events) != 1 || events[0].Op != Write || events[0].Path != linked {
+		t.Errorf("expected a single write event for %s, got %v", linked, events)
+	}
+
+	// Point the symlink to another target.
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(testDir, link); err != nil {
+		t.Fatal(err)
+	}
+	var replaced, removed, created bool
+	for _, event := range pollOnce(w) {
+		switch {
+		case event.Op == Replace && event.Path == link:
+			replaced = true
+		case event.Op == Remove && event.Path == linked, event.Op == Move && event.OldPath == linked:
+			// testDirTwo is still linked through the new target, it's moved.
+			removed = true
+		case event.Op == Create && event.Path == filepath.Join(link, "file.txt"):
+			created = true
+		}
+	}
+	if !replaced || !removed || !created {
+		t.Errorf("expected the symlink to be replaced and its children to be updated, got replaced %t, removed %t, created %t", replaced, removed, created)
+	}
+	// testDir contains the watched directory, so it isn't followed again.
+	if node := w.RetrieveAllNodes()[filepath.Join(link, "watched")]; !node.ignored {
+		t.Error("expected the cycle through the retargeted symlink not to be followed")
+	}
+}
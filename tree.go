@@ -0,0 +1,175 @@
+package gowatcher
+
+import (
+	"regexp"
+	"sort"
+)
+
+// SnapshotOptions configures how Snapshot traverses a path and how Diff compares the trees.
+// They have the same meaning as the corresponding GoWatcher settings.
+type SnapshotOptions struct {
+	Recursive    bool
+	IgnoreHidden bool
+	IgnoreNames  []string // regexes matched against the names, like IgnoreName.
+	IgnorePaths  []string // regexes matched against the paths, like IgnorePath.
+	// IgnorePatterns uses the .gitignore syntax like IgnorePatterns, and Gitignore loads the .gitignore files.
+	IgnorePatterns []string
+	Gitignore      bool
+	// IncludeGlobs and ExcludeGlobs are relative to the path, like IncludeGlob and ExcludeGlob.
+	IncludeGlobs []string
+	ExcludeGlobs []string
+	ChangePolicy ChangePolicy
+	// HashAlgorithm and HashMaxSize are used to hash the content of files if HashContents is true.
+	HashContents  bool
+	HashAlgorithm HashAlgorithm
+	HashMaxSize   int64
+	FileSystem    FileSystem // OSFileSystem if nil.
+}
+
+// Tree is the state of a path captured by Snapshot.
+type Tree struct {
+	Path string
+	Root *FileNode // nil if the path itself is ignored.
+
+	w *GoWatcher // compares the nodes of the tree.
+}
+
+// Snapshot captures the state of a path without running a gowatcher. The path is traversed with
+// the same rules as AddPath, a zero ChangePolicy means DefaultChangePolicy.
+func Snapshot(path string, opts SnapshotOptions) (*Tree, error) {
+	w := New()
+	w.ignoreHidden = opts.IgnoreHidden
+	for _, s := range opts.IgnoreNames {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		w.nameIgnores = append(w.nameIgnores, re)
+	}
+	if err := w.IgnorePath(opts.IgnorePaths...); err != nil {
+		return nil, err
+	}
+	if err := w.IgnorePatterns(opts.IgnorePatterns...); err != nil {
+		return nil, err
+	}
+	w.loadGitignore = opts.Gitignore
+	if err := w.IncludeGlob(opts.IncludeGlobs...); err != nil {
+		return nil, err
+	}
+	if err := w.ExcludeGlob(opts.ExcludeGlobs...); err != nil {
+		return nil, err
+	}
+	if opts.ChangePolicy != 0 {
+		w.changePolicy = opts.ChangePolicy
+	}
+	if opts.HashContents {
+		w.HashContents(opts.HashAlgorithm, opts.HashMaxSize)
+	}
+	if opts.FileSystem != nil {
+		w.fsys = opts.FileSystem
+	}
+
+	path, err := w.fsys.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.AddPath(path, opts.Recursive); err != nil {
+		return nil, err
+	}
+	tree := &Tree{Path: path, w: w}
+	if t, found := w.fileTrees[path]; found {
+		tree.Root = t.root
+	}
+	return tree, nil
+}
+
+// Diff returns the events that turn the old tree into the new one, using the same ops as
+// a polling cycle and the options of the new tree. The events are sorted deterministically:
+// the changed nodes come first in path order, then the removed, created and moved ones.
+// Either tree can be nil: if the new tree is nil, its root is removed using the options of the
+// old tree, and if the old tree is nil, every node of the new tree is created. Diff returns nil
+// if both trees are nil.
+func Diff(old, cur *Tree) []Event {
+	ref := cur
+	if ref == nil {
+		ref = old
+	}
+	if ref == nil {
+		return nil
+	}
+	d := &differ{w: ref.w}
+	var oldRoot, curRoot *FileNode
+	if old != nil {
+		oldRoot = old.Root
+	}
+	if cur != nil {
+		curRoot = cur.Root
+	}
+	d.diff(oldRoot, curRoot)
+
+	sort.Slice(d.removed, func(i, j int) bool { return d.removed[i].Path < d.removed[j].Path })
+	sort.Slice(d.created, func(i, j int) bool { return d.created[i].Path < d.created[j].Path })
+	events := append(d.changed, pairMoves(d.removed, d.created, d.w.fsys.SameFile)...)
+	for i := range events {
+		events[i].setRoot(ref.Path)
+	}
+	return events
+}
+
+// differ holds the events found while comparing two trees.
+type differ struct {
+	w       *GoWatcher
+	changed []Event
+	created []Event
+	removed []Event
+}
+
+// diff compares two nodes of the same path like pollNode compares a node to the filesystem.
+func (d *differ) diff(old, cur *FileNode) {
+	switch {
+	case old == nil && cur == nil:
+		return
+	case old == nil:
+		d.create(cur)
+		return
+	case cur == nil:
+		if !old.ignored {
+			d.removed = append(d.removed, Event{Op: Remove, Path: old.Path, FileInfo: old.Info, Digest: old.Digest})
+		}
+		return
+	case old.ignored || cur.ignored:
+		return
+	}
+	d.changed = append(d.changed, d.w.changedEvents(old, cur)...)
+
+	for _, name := range childNames(old, cur) {
+		d.diff(old.Children[name], cur.Children[name])
+	}
+}
+
+// create adds the Create events of a node and its descendants.
+func (d *differ) create(node *FileNode) {
+	if node.ignored {
+		return
+	}
+	d.created = append(d.created, Event{Op: Create, Path: node.Path, FileInfo: node.Info, Digest: node.Digest})
+	for _, name := range childNames(node) {
+		d.create(node.Children[name])
+	}
+}
+
+// childNames returns the sorted names of the children of the nodes.
+func childNames(nodes ...*FileNode) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, node := range nodes {
+		for name, child := range node.Children {
+			if child != nil && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+package gowatcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	opts := SnapshotOptions{Recursive: true, IgnoreHidden: true}
+	old, err := Snapshot(testDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := Diff(old, old); len(events) != 0 {
+		t.Errorf("expected no events between identical trees, got %v", events)
+	}
+	nodes := len(old.Root.RetrieveAllNodes())
+	if events := Diff(old, nil); len(events) != 1 || events[0].Op != Remove || events[0].Path != testDir {
+		t.Errorf("expected a remove event for %s against a nil tree, got %v", testDir, events)
+	}
+	if events := Diff(nil, old); len(events) != nodes || events[0].Op != Create {
+		t.Errorf("expected %d create events from a nil tree, got %v", nodes, events)
+	}
+	if events := Diff(nil, nil); events != nil {
+		t.Errorf("expected no events between nil trees, got %v", events)
+	}
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	file1 := filepath.Join(testDir, "file_1.txt")
+	renamed := filepath.Join(testDir, "renamed.txt")
+	newDir := filepath.Join(testDir, "newDir")
+	newFile := filepath.Join(newDir, "new.txt")
+	if err := chtimes(fileTxt, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(file1, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(newDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Hidden files are ignored like they are by AddPath.
+	if err := ioutil.WriteFile(filepath.Join(testDir, ".hidden"), []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cur, err := Snapshot(testDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type change struct {
+		Op      Op
+		Path    string
+		OldPath string
+	}
+	var changes []change
+	for _, event := range Diff(old, cur) {
+		if event.Op == Write && event.IsDir() {
+			continue
+		}
+		changes = append(changes, change{event.Op, event.Path, event.OldPath})
+	}
+	expected := []change{
+		{Write, fileTxt, ""},
+		{Rename, renamed, file1},
+		{Create, newDir, ""},
+		{Create, newFile, ""},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+
+	// The diff is deterministic.
+	for i := 0; i < 10; i++ {
+		if !reflect.DeepEqual(Diff(old, cur), Diff(old, cur)) {
+			t.Fatal("expected the same events for the same trees")
+		}
+	}
+}
+
+func TestSnapshotInvalidPattern(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	if _, err := Snapshot(testDir, SnapshotOptions{IgnoreNames: []string{"("}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
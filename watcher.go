@@ -1,11 +1,12 @@
 package gowatcher
 
 import (
+	"context"
 	"errors"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sync"
 	"time"
 )
@@ -25,28 +26,69 @@ var (
 	ErrWatchedFileDeleted = errors.New("error: watched file or folder deleted")
 
 	ErrWatchSymlink = errors.New("error: watch symlink")
+
+	// ErrSnapshotVersion occurs when loading a snapshot written
+	// in a format that this version of gowatcher doesn't support.
+	ErrSnapshotVersion = errors.New("error: unsupported snapshot version")
+
+	// ErrBackendUnsupported occurs when creating a backend
+	// that isn't supported on the current platform.
+	ErrBackendUnsupported = errors.New("error: backend is not supported on this platform")
 )
 
 // Watcher describes a process that watches files for changes.
 type GoWatcher struct {
-	Event  chan Event
-	Error  chan error
-	Closed chan struct{}
-	close  chan struct{}
-	wg     *sync.WaitGroup
-
-	// mu protects the following.
-	mu      *sync.RWMutex
+	Event     chan Event
+	Batches   chan Batch
+	Error     chan error
+	Closed    chan struct{}
+	closeOnce sync.Once
+	wg        *sync.WaitGroup
+	startOnce sync.Once
+
+	// runMu protects the following.
+	runMu   sync.Mutex
 	running bool
+	stop    context.CancelFunc // stops the running polling cycle
+	stopped chan struct{}      // closed once the running polling cycle has returned
 
-	fileTrees    map[string]*FileNode // map of FileNode trees, every added path will be inserted here
-	nameFilters  []*regexp.Regexp
-	nameIgnores  []*regexp.Regexp
-	pathFilters  []*regexp.Regexp
-	pathIgnores  []*regexp.Regexp
-	ops          map[Op]struct{} // Op filtering.
-	ignoreHidden bool            // ignore hidden files or not.
-	maxEvents    int             // max sent events per cycle
+	// mu protects the following.
+	mu *sync.RWMutex
+
+	fileTrees    map[string]*fileTree // map of FileNode trees, every added path will be inserted here
+	rootErrors   []error              // errors of the deleted roots, sent after the polling cycle
+	pendingPaths bool                 // accept added paths that don't exist yet
+	filterSet                         // filters of the paths added without their own options
+	maxEvents    int                  // max sent events per cycle
+
+	loadGitignore  bool // load the .gitignore files of the watched directories.
+	followSymlinks bool // follow the symlinks instead of ignoring them.
+
+	announceUnignored bool    // send Create events for the files that aren't ignored anymore.
+	unignored         []Event // Create events of the un-ignored files, sent by the next polling cycle.
+
+	initialEvents bool    // send the Exists events of the content of the added paths.
+	initial       []Event // Exists and Ready events of the added paths, sent by the next polling cycle.
+
+	hashContents  bool              // detect writes by hashing the content of files.
+	hashAlgorithm HashAlgorithm     // algorithm used to hash the content of files.
+	hashMaxSize   int64             // files bigger than this are not hashed, no limit if less than 1.
+	changePolicy  ChangePolicy      // file attributes compared to detect a change.
+	concurrency   int               // max number of goroutines polling the file trees.
+	adaptive      *AdaptiveInterval // adapts the polling interval to the activity, nil for a fixed interval.
+	fsys          FileSystem        // the watched filesystem.
+	backend       Backend           // notifies changed directories, nil to only poll.
+	reconcile     time.Duration     // polling interval of the paths covered by the backend.
+	debounce      *debouncer        // holds the events until their path is quiet, nil to send them right away.
+	batchMode     bool              // send the events of a cycle on the Batches channel.
+	batchSeq      uint64            // sequence number of the last sent batch.
+	eventSeq      uint64            // sequence number of the last found event.
+
+	noEventChannel bool // only send the events to the subscriptions.
+
+	// subMu protects the subscriptions, which are read while w.mu is held by the polling cycle.
+	subMu sync.RWMutex
+	subs  []*Subscription
 }
 
 // New creates a new Watcher.
@@ -57,17 +99,15 @@ func New() *GoWatcher {
 
 	return &GoWatcher{
 		Event:        make(chan Event),
+		Batches:      make(chan Batch),
 		Error:        make(chan error),
 		Closed:       make(chan struct{}),
-		close:        make(chan struct{}),
 		mu:           new(sync.RWMutex),
 		wg:           &wg,
-		fileTrees:    make(map[string]*FileNode),
-		nameFilters:  make([]*regexp.Regexp, 0),
-		nameIgnores:  make([]*regexp.Regexp, 0),
-		pathFilters:  make([]*regexp.Regexp, 0),
-		pathIgnores:  make([]*regexp.Regexp, 0),
-		ignoreHidden: false,
+		fileTrees:    make(map[string]*fileTree),
+		changePolicy: DefaultChangePolicy,
+		concurrency:  1,
+		fsys:         OSFileSystem{},
 	}
 }
 
@@ -80,11 +120,25 @@ func (w *GoWatcher) SetMaxEvents(delta int) *GoWatcher {
 	return w
 }
 
+// SetConcurrency sets the maximum number of goroutines that poll the file trees during a cycle.
+// Independent roots and directories are then polled in parallel, the events of a single path are
+// still sent in order. If n is less than 1, runtime.NumCPU() goroutines are used. The default is 1.
+func (w *GoWatcher) SetConcurrency(n int) *GoWatcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	w.concurrency = n
+	return w
+}
+
 // IgnoreHiddenFiles sets the gowatcher to ignore any file or directory
 // that starts with a dot.
 func (w *GoWatcher) IgnoreHiddenFiles(ignore bool) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
 	w.ignoreHidden = ignore
 }
 
@@ -92,6 +146,7 @@ func (w *GoWatcher) IgnoreHiddenFiles(ignore bool) {
 func (w *GoWatcher) IgnoreName(s ...string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
 	for _, a := range s {
 		w.nameIgnores = append(w.nameIgnores, regexp.MustCompile(a))
 	}
@@ -110,6 +165,7 @@ func (w *GoWatcher) FilterName(s ...string) {
 func (w *GoWatcher) IgnorePath(s ...string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	defer w.reapplyRules(w.filterSet)
 	for _, a := range s {
 		absPath, err := filepath.Abs(a)
 		if err != nil {
@@ -142,16 +198,16 @@ func (w *GoWatcher) FilterPath(s ...string) error {
 	return nil
 }
 
-func (w *GoWatcher) shouldIgnore(name string, path string) bool {
-	if len(w.nameIgnores) == 0 && len(w.pathIgnores) == 0 {
+func (f *filterSet) shouldIgnore(name string, path string) bool {
+	if len(f.nameIgnores) == 0 && len(f.pathIgnores) == 0 {
 		return false
 	}
-	for _, reg := range w.nameIgnores {
+	for _, reg := range f.nameIgnores {
 		if reg.MatchString(name) {
 			return true
 		}
 	}
-	for _, reg := range w.pathIgnores {
+	for _, reg := range f.pathIgnores {
 		if reg.MatchString(path) {
 			return true
 		}
@@ -159,16 +215,16 @@ func (w *GoWatcher) shouldIgnore(name string, path string) bool {
 	return false
 }
 
-func (w *GoWatcher) shouldNotice(name string, path string) bool {
-	if len(w.nameFilters) == 0 && len(w.pathFilters) == 0 {
+func (f *filterSet) shouldNotice(name string, path string) bool {
+	if len(f.nameFilters) == 0 && len(f.pathFilters) == 0 {
 		return true
 	}
-	for _, reg := range w.nameFilters {
+	for _, reg := range f.nameFilters {
 		if reg.MatchString(name) {
 			return true
 		}
 	}
-	for _, reg := range w.pathFilters {
+	for _, reg := range f.pathFilters {
 		if reg.MatchString(path) {
 			return true
 		}
@@ -191,69 +247,133 @@ func (w *GoWatcher) FilterOps(ops ...Op) {
 // Parameter recursive determine whether the path be loaded recursively.
 // Notice: This function should be called after ignore and filter!
 func (w *GoWatcher) AddPath(path string, recursive bool) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	return w.addPath(path, maxDepth(recursive), 0, 0, nil, nil)
+}
 
-	path, err := filepath.Abs(path)
-	if err != nil {
-		return err
+// AddPathWithInterval adds a path like AddPath, but the path is polled on its own
+// timer every interval instead of with the duration passed to Start.
+func (w *GoWatcher) AddPathWithInterval(path string, recursive bool, interval time.Duration) error {
+	if interval < time.Nanosecond {
+		return ErrDurationTooShort
 	}
+	return w.addPath(path, maxDepth(recursive), interval, 0, nil, nil)
+}
+
+// AddPathWithPriority adds a path like AddPath with a priority. When several paths are due
+// in the same cycle, the ones with a higher priority are polled and notified first.
+// The priority of paths added by AddPath is 0.
+func (w *GoWatcher) AddPathWithPriority(path string, recursive bool, priority int) error {
+	return w.addPath(path, maxDepth(recursive), 0, priority, nil, nil)
+}
+
+// AddPathWithDepth adds a path like AddPath, but only watches maxDepth levels of directories below it.
+// The directories at the last level are still watched, but their content isn't. A maxDepth of 0 only
+// watches the path itself, 1 also watches its content like a non-recursive AddPath, and there is no
+// limit if maxDepth is negative.
+func (w *GoWatcher) AddPathWithDepth(path string, maxDepth int) error {
+	return w.addPath(path, maxDepth, 0, 0, nil, nil)
+}
+
+// maxDepth returns the maximum depth of a recursive or non-recursive path.
+func maxDepth(recursive bool) int {
+	if recursive {
+		return -1
+	}
+	return 1
+}
+
+// addPath adds a path with its settings, the filters are nil for the gowatcher's ones.
+func (w *GoWatcher) addPath(path string, maxDepth int, interval time.Duration, priority int, filters *filterSet, opts *Options) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	stat, err := os.Lstat(path)
+	path, err := w.fsys.Abs(path)
 	if err != nil {
 		return err
 	}
-	if stat.Mode()&os.ModeSymlink != 0 {
-		return ErrWatchSymlink
-	}
 
 	// If path is already added, just return
 	if _, existed := w.fileTrees[path]; existed {
 		return nil
 	}
 
+	stat, err := w.fsys.Lstat(path)
+	if err != nil {
+		if !w.pendingPaths || !os.IsNotExist(err) {
+			return err
+		}
+		// The root is attached once it appears.
+		w.fileTrees[path] = &fileTree{
+			path: path, maxDepth: maxDepth, interval: interval, priority: priority, filters: filters, opts: opts,
+		}
+		return nil
+	}
+	if stat.Mode()&os.ModeSymlink != 0 && !w.followSymlinks {
+		return ErrWatchSymlink
+	}
+
 	// If hidden files are ignored and path is a hidden file or directory, simply return.
-	isHidden, err := isHiddenFile(path)
+	isHidden, err := w.fsys.IsHidden(path)
 	if err != nil {
 		return err
 	}
-	if w.shouldIgnore(stat.Name(), path) || (w.ignoreHidden && isHidden) {
+	f := w.filtersOf(filters)
+	if f.shouldIgnore(stat.Name(), path) || (f.ignoreHidden && isHidden) {
 		return nil
 	}
 
 	// Traverse the path and its content to get a root file node.
-	fileNode, err := w.traverseTree(path, recursive)
-	// The outside fileNode's recursive is always true
-	fileNode.recursive = true
+	fileNode, err := w.traverseNode(path, maxDepth, w.rootRules(path, filters), nil)
 	if err != nil {
 		return err
 	}
 
 	// Add the root node to file trees.
-	w.fileTrees[path] = fileNode
+	t := &fileTree{
+		path: path, root: fileNode, maxDepth: maxDepth, interval: interval, priority: priority, filters: filters, opts: opts,
+	}
+	w.fileTrees[path] = t
+	w.watchTree(t)
+	if w.initialEvents {
+		w.listTree(t)
+	}
 
 	return nil
 }
 
-// Generate the first added path and create a file node for every file. This function can be recursively called.
+// Generate the first added path and create a file node for every file.
 func (w *GoWatcher) traverseTree(path string, recursive bool) (node *FileNode, err error) {
+	return w.traverseNode(path, maxDepth(recursive), w.rootRules(path, nil), nil)
+}
+
+// traverseNode creates the file node of a path and its descendants up to depth levels below it, or all of them
+// if depth is negative. This function can be recursively called. The ignore rules are the ones applying to the path,
+// from IgnorePatterns and the .gitignore files of its parents. The parent node is nil for the root of a tree.
+func (w *GoWatcher) traverseNode(path string, depth int, rules *ignoreRules, parent *FileNode) (node *FileNode, err error) {
 
 	// Make sure path exists.
-	stat, err := os.Lstat(path)
+	stat, err := w.fsys.Lstat(path)
 	if err != nil {
 		return node, err
 	}
+	stat, link := w.resolve(path, stat)
 
-	node = newNode(path, stat, recursive, w.shouldIgnore(stat.Name(), path))
+	ignored := w.ignored(rules, stat.Name(), path, stat.IsDir()) || w.followSymlinks && w.isCycle(parent, stat)
+	node = newNode(path, stat, depth, ignored)
+	node.Link, node.parent = link, parent
+	if !node.ignored {
+		w.fillNode(node)
+	}
 
-	// If it's not a directory or it's ignored, just return it.
-	if !stat.IsDir() || node.ignored {
+	// If it's not a directory, it's ignored or its content isn't watched, just return it.
+	if !stat.IsDir() || node.ignored || depth == 0 {
 		return node, nil
 	}
+	node.rules = w.dirRules(rules, path)
 	childMap := make(map[string]*FileNode)
 
 	// It's a directory.
-	infoList, err := ioutil.ReadDir(path)
+	infoList, err := w.fsys.ReadDir(path)
 	if err != nil {
 		return node, err
 	}
@@ -265,21 +385,25 @@ func (w *GoWatcher) traverseTree(path string, recursive bool) (node *FileNode, e
 		name := info.Name()
 		path := filepath.Join(path, name)
 
-		isHidden, err := isHiddenFile(path)
+		isHidden, err := w.fsys.IsHidden(path)
 		if err != nil {
 			return node, err
 		}
 
-		shouldIgnore := w.shouldIgnore(name, path)
-		if shouldIgnore || (w.ignoreHidden && isHidden) {
+		shouldIgnore := w.ignored(node.rules, name, path, info.IsDir())
+		if shouldIgnore || (w.filtersOf(rules.filterSet()).ignoreHidden && isHidden) {
 			continue
 		}
 		//fmt.Println(path)
 
-		if !recursive {
-			childMap[name] = newNode(path, info, false, shouldIgnore)
-		} else if !shouldIgnore {
-			childMap[name], _ = w.traverseTree(path, true)
+		if node.childDepth() == 0 {
+			info, link := w.resolve(path, info)
+			child := newNode(path, info, 0, shouldIgnore)
+			child.Link, child.parent = link, node
+			w.fillNode(child)
+			childMap[name] = child
+		} else {
+			childMap[name], _ = w.traverseNode(path, node.childDepth(), node.rules, node)
 		}
 
 	}
@@ -293,11 +417,12 @@ func (w *GoWatcher) Remove(path string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	path, err := filepath.Abs(path)
+	path, err := w.fsys.Abs(path)
 	if err != nil {
 		return err
 	}
-	if _, exist := w.fileTrees[path]; exist {
+	if t, exist := w.fileTrees[path]; exist {
+		w.unwatchTree(t)
 		delete(w.fileTrees, path)
 	}
 	return nil
@@ -311,198 +436,203 @@ func (w *GoWatcher) TriggerEvent(eventType Op, file os.FileInfo) {
 
 		file = &fileInfo{name: "triggered event", modTime: time.Now()}
 	}
-	w.Event <- Event{Op: eventType, Path: "-", FileInfo: file}
+	w.Event <- Event{Op: eventType, Path: "-", FileInfo: file, Time: time.Now()}
 }
 
 // Start begins the polling cycle which repeats every specified
 // duration until Close is called.
 func (w *GoWatcher) Start(d time.Duration) error {
+	return w.StartContext(context.Background(), d)
+}
+
+// StartContext begins the polling cycle which repeats every specified duration until
+// the context is cancelled or Close is called. It waits for the current polling cycle
+// to finish before returning, and keeps the file trees so that the gowatcher can be started
+// again. It returns the context's error if the context was cancelled.
+func (w *GoWatcher) StartContext(ctx context.Context, d time.Duration) error {
 	// Return an error if d is less than 1 nanosecond.
 	if d < time.Nanosecond {
 		return ErrDurationTooShort
 	}
 
 	// Make sure the Watcher is not already running.
-	w.mu.Lock()
+	w.runMu.Lock()
 	if w.running {
-		w.mu.Unlock()
+		w.runMu.Unlock()
 		return ErrWatcherRunning
 	}
-	w.running = true
-	w.mu.Unlock()
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	w.running, w.stop, w.stopped = true, stop, stopped
+	w.runMu.Unlock()
+
+	defer func() {
+		stop()
+		w.runMu.Lock()
+		w.running, w.stop, w.stopped = false, nil, nil
+		w.runMu.Unlock()
+		close(stopped)
+	}()
+
+	w.mu.RLock()
+	s := newScheduler(d, w.adaptive)
+	var changes <-chan string
+	var backendErrors <-chan error
+	if w.backend != nil {
+		changes, backendErrors = w.backend.Changes(), w.backend.Errors()
+	}
+	w.mu.RUnlock()
 
 	// Unblock w.Wait().
-	w.wg.Done()
+	w.startOnce.Do(w.wg.Done)
 
+	// The paths without their own interval are polled together, on the scheduler's interval.
+	var due time.Time
 	for {
-		// done lets the inner polling cycle loop know when the
-		// current cycle's method has finished executing.
-		done := make(chan struct{})
-
-		// Any events that are found are first piped to evt before
-		// being sent to the main Event channel.
-		evt := make(chan Event)
-
-		// cancel can be used to cancel the current event polling function.
-		cancel := make(chan struct{})
-		// Look for events.
-		go func() {
-			w.pollEvents(evt, cancel)
-			done <- struct{}{}
-		}()
-
-		// numEvents holds the number of events for the current cycle.
-		numEvents := 0
-
-	inner:
+		pollDefault := !time.Now().Before(due)
+		events, scan := w.cycle(runCtx, func(evt chan Event, cancel chan struct{}) {
+			now := time.Now()
+			w.pollEvents(evt, cancel, func(t *fileTree) bool {
+				if w.treeInterval(t) > 0 {
+					return !now.Before(t.next)
+				}
+				return pollDefault
+			})
+		})
+		if pollDefault {
+			due = time.Now().Add(s.next(events > 0, scan))
+		}
+		w.flushDebounced(runCtx)
+		w.sendRootErrors(runCtx)
+
+		// Sleep until the next path is due and then continue to the next loop iteration.
+		// In the meantime, the directories reported by the backend are polled right away.
+		next := due
+		if t, ok := w.nextDue(); ok && t.Before(next) {
+			next = t
+		}
+		timer := time.NewTimer(time.Until(next))
+		quiet := w.quietAfter()
+	wait:
 		for {
 			select {
-			case <-w.close:
-				close(cancel)
-				close(w.Closed)
-				return nil
-			case event := <-evt:
-				if len(w.ops) > 0 { // Filter Ops.
-					_, found := w.ops[event.Op]
-					if !found {
-						continue
-					}
-				}
-				if !w.shouldNotice(event.Name(), event.Path) {
-					continue
-				}
-				numEvents++
-				if w.maxEvents > 0 && numEvents > w.maxEvents {
-					close(cancel)
-					break inner
+			case <-runCtx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+				break wait
+			case <-quiet:
+				w.flushDebounced(runCtx)
+				quiet = w.quietAfter()
+			case path := <-changes:
+				paths := drainChanges(changes, path)
+				if paths == nil {
+					// The backend lost track of the changes, reconcile every path.
+					w.resetDue()
+					due = time.Time{}
+					timer.Stop()
+					break wait
 				}
-				w.Event <- event
-			case <-done: // Current cycle is finished.
-				break inner
+				w.cycle(runCtx, func(evt chan Event, cancel chan struct{}) {
+					w.pollChanged(evt, cancel, paths)
+				})
+				w.flushDebounced(runCtx)
+				w.sendRootErrors(runCtx)
+				quiet = w.quietAfter()
+			case <-backendErrors:
+				// The backend can't be trusted anymore, fall back to polling.
+				w.dropBackend()
+				changes, backendErrors = nil, nil
+				due = time.Time{}
+				timer.Stop()
+				break wait
 			}
 		}
-
-		// Update the file's traverseTree.
-		//w.mu.Lock()
-		//w.files = fileList
-		//w.mu.Unlock()
-
-		// Sleep and then continue to the next loop iteration.
-		time.Sleep(d)
 	}
 }
 
-func (w *GoWatcher) pollEvents(evt chan Event, cancel chan struct{}) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	for k, v := range w.fileTrees {
-		w.fileTrees[k] = w.pollNodeEvent(v, evt, cancel)
-	}
-}
-
-// To get every node's change and generate events.
-func (w *GoWatcher) pollNodeEvent(node *FileNode, evt chan Event, cancel chan struct{}) *FileNode {
-	if node == nil {
-		return nil
-	}
-	node.mu.Lock()
-	defer node.mu.Unlock()
-	// If the node was ignored, don't need to check it and just return
-	if node.ignored {
-		return node
-	}
-	// Check if the path was removed
-	newInfo, err := os.Lstat(node.Path)
-	if err != nil {
-		evt <- Event{Remove, node.Path, node.Info}
-		return nil
-	}
-	// Compare old info and new info
-	if node.Info.ModTime() != newInfo.ModTime() {
-		select {
-		case <-cancel:
-			return node
-		case evt <- Event{Write, node.Path, newInfo}:
-		}
-	}
-	if node.Info.Mode() != newInfo.Mode() {
-		select {
-		case <-cancel:
-			return node
-		case evt <- Event{Chmod, node.Path, newInfo}:
+// cycle runs a single polling cycle and sends the events it finds on the Event channel,
+// or as a single batch on the Batches channel in batch mode. It only returns once poll
+// has returned, with the number of events that were found and the time poll took.
+func (w *GoWatcher) cycle(ctx context.Context, poll func(evt chan Event, cancel chan struct{})) (events int, scan time.Duration) {
+	// done lets the cycle know when the current poll call has finished executing.
+	done := make(chan struct{})
+
+	// Any events that are found are first piped to evt before
+	// being sent to the main Event channel.
+	evt := make(chan Event)
+
+	// cancel can be used to cancel the current event polling function.
+	cancel := make(chan struct{})
+	stop := func() {
+		close(cancel)
+		<-done
+	}
+
+	// Look for events.
+	start := time.Now()
+	go func() {
+		poll(evt, cancel)
+		scan = time.Since(start)
+		close(done)
+	}()
+
+	// numEvents holds the number of events sent for the current cycle.
+	numEvents := 0
+	// batch holds the events of the cycle in batch mode.
+	var batch []Event
+	sendBatch := func() {
+		if w.batchMode && !w.noEventChannel {
+			w.sendBatch(ctx, Batch{Start: start, Duration: scan, Events: batch})
 		}
 	}
 
-	node.Info = newInfo
-	// If it's not a directory or marked as non-recursive, just return.
-	if !newInfo.IsDir() || !node.recursive {
-		return node
-	}
-	// It's a directory.
-	infoList, err := ioutil.ReadDir(node.Path)
-	if err != nil {
-		return node
-	}
-	// Check new file list
-	infoMap := make(map[string]os.FileInfo)
-	for _, info := range infoList {
-		name := info.Name()
-		path := filepath.Join(node.Path, name)
-
-		isHidden, err := isHiddenFile(path)
-		if err != nil {
-			return node
-		}
-
-		if w.ignoreHidden && isHidden {
-			continue
-		}
-		child, exist := node.Children[name]
-		if exist {
-			if child.ignored {
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			return events, scan
+		case event := <-evt:
+			events++
+			w.eventSeq++
+			event.Seq = w.eventSeq
+			if !w.publish(ctx, event) {
+				stop()
+				return events, scan
+			}
+			if w.noEventChannel {
 				continue
 			}
-
-			node.Children[name] = w.pollNodeEvent(child, evt, cancel)
-		} else {
-			newChild := newNode(path, info, node.recursive, w.shouldIgnore(name, path))
-			node.Children[name] = newChild
-			if newChild.ignored {
+			// The events are filtered by the options of their root.
+			if !w.filtersOf(event.filters).notice(event) {
 				continue
 			}
-			select {
-			case <-cancel:
-				return node
-			case evt <- Event{Create, path, info}:
+			numEvents++
+			if w.maxEvents > 0 && numEvents > w.maxEvents {
+				stop()
+				sendBatch()
+				return events, scan
 			}
-			w.pollNodeEvent(newChild, evt, cancel)
-		}
-		infoMap[info.Name()] = info
-	}
-	// Examine every node.
-	for k, childNode := range node.Children {
-		if childNode == nil {
-			//fmt.Println("find nil child node")
-			delete(node.Children, k)
-			continue
-		}
-		if _, exist := infoMap[k]; exist {
-			delete(infoMap, k)
-			node.Children[k] = w.pollNodeEvent(childNode, evt, cancel)
-		} else {
-			delete(node.Children, k)
-			if childNode.ignored {
+			// The initial listing isn't held, so that Ready still follows it.
+			if w.debounce != nil && event.Op != Exists && event.Op != Ready {
+				w.debounce.add(event, time.Now())
+				continue
+			}
+			if w.batchMode {
+				batch = append(batch, event)
 				continue
 			}
 			select {
-			case <-cancel:
-				return node
-			case evt <- Event{Remove, childNode.Path, childNode.Info}:
-				//fmt.Printf("Doesn't exist in infoMap : isDir: %v, pWritten: %v\n", childNode.Info.IsDir(), parentDirWritten)
+			case <-ctx.Done():
+				stop()
+				return events, scan
+			case w.Event <- event:
 			}
+		case <-done: // Current cycle is finished.
+			sendBatch()
+			return events, scan
 		}
 	}
-	return node
 }
 
 // Wait blocks until the gowatcher is started.
@@ -510,24 +640,30 @@ func (w *GoWatcher) Wait() {
 	w.wg.Wait()
 }
 
-// Close stops a Watcher and unlocks its mutex, then sends a close signal.
+// Close stops a running gowatcher, waits for its polling cycle to return and then
+// closes the Closed channel. The file trees are kept, so the gowatcher can be started again.
 func (w *GoWatcher) Close() {
-	w.mu.Lock()
-	if !w.running {
-		w.mu.Unlock()
-		return
-	}
-	w.running = false
-	w.fileTrees = nil
-	w.mu.Unlock()
-	// Send a close signal to the Start method.
-	w.close <- struct{}{}
+	w.runMu.Lock()
+	stop, stopped := w.stop, w.stopped
+	w.runMu.Unlock()
+	if stop != nil {
+		stop()
+		<-stopped
+	}
+	w.closeOnce.Do(func() {
+		close(w.Closed)
+	})
 }
 
 func (w *GoWatcher) RetrieveAllNodes() (files map[string]FileNode) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	files = make(map[string]FileNode)
-	for _, v := range w.fileTrees {
-		c := v.RetrieveAllNodes()
+	for _, t := range w.fileTrees {
+		if t.root == nil {
+			continue
+		}
+		c := t.root.RetrieveAllNodes()
 		for k, v := range c {
 			files[k] = v
 		}
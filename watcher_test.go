@@ -1,10 +1,12 @@
 package gowatcher
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -88,6 +90,12 @@ func TestEventString(t *testing.T) {
 			t.Errorf("expected e.String() to be %s, got %s", tc.expected, e.String())
 		}
 	}
+
+	e = &Event{Op: Rename, Path: "/fake/new", OldPath: "/fake/old", FileInfo: &fileInfo{name: "new"}}
+	expected := "FILE \"new\" RENAME [/fake/old -> /fake/new]"
+	if e.String() != expected {
+		t.Errorf("expected e.String() to be %s, got %s", expected, e.String())
+	}
 }
 
 func TestFileInfo(t *testing.T) {
@@ -806,7 +814,14 @@ func TestOpsString(t *testing.T) {
 		{Write, "WRITE"},
 		{Remove, "REMOVE"},
 		{Chmod, "CHMOD"},
-		{Op(10), "???"},
+		{Rename, "RENAME"},
+		{Move, "MOVE"},
+		{Replace, "REPLACE"},
+		{Chown, "CHOWN"},
+		{Xattr, "XATTR"},
+		{Exists, "EXISTS"},
+		{Ready, "READY"},
+		{Op(11), "???"},
 	}
 
 	for _, tc := range testCases {
@@ -815,3 +830,459 @@ func TestOpsString(t *testing.T) {
 		}
 	}
 }
+
+// pollOnce runs a single polling cycle and returns the events it sent.
+func pollOnce(w *GoWatcher) []Event {
+	evt := make(chan Event)
+	done := make(chan struct{})
+	go func() {
+		w.pollEvents(evt, make(chan struct{}), nil)
+		close(done)
+	}()
+
+	var events []Event
+	for {
+		select {
+		case event := <-evt:
+			events = append(events, event)
+		case <-done:
+			return events
+		}
+	}
+}
+
+func TestEventRenameFile(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath := filepath.Join(testDir, "file_1.txt")
+	newPath := filepath.Join(testDir, "renamed.txt")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var renames []Event
+	for _, event := range pollOnce(w) {
+		switch event.Op {
+		case Rename:
+			renames = append(renames, event)
+		case Create, Remove:
+			t.Errorf("expected no %s event, got %s", event.Op, event)
+		}
+	}
+	if len(renames) != 1 {
+		t.Fatalf("expected 1 rename event, got %d", len(renames))
+	}
+	if renames[0].OldPath != oldPath || renames[0].Path != newPath {
+		t.Errorf("expected rename from %s to %s, got %s", oldPath, newPath, renames[0])
+	}
+}
+
+func TestEventMoveDirectory(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(testDir, "testDirThree")
+	if err := os.Mkdir(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pollOnce(w)
+
+	oldPath := filepath.Join(testDir, "testDirTwo")
+	newPath := filepath.Join(dst, "testDirTwo")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var moves []Event
+	for _, event := range pollOnce(w) {
+		switch event.Op {
+		case Move:
+			moves = append(moves, event)
+		case Create, Remove:
+			t.Errorf("expected no %s event, got %s", event.Op, event)
+		}
+	}
+	if len(moves) != 1 {
+		t.Fatalf("expected 1 move event, got %d", len(moves))
+	}
+	if moves[0].OldPath != oldPath || moves[0].Path != newPath || !moves[0].IsDir() {
+		t.Errorf("expected directory move from %s to %s, got %s", oldPath, newPath, moves[0])
+	}
+}
+
+func TestStartContext(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	w.FilterOps(Create)
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, time.Millisecond*10)
+	}()
+	w.Wait()
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled error, got %v", err)
+		}
+	case <-time.After(time.Millisecond * 250):
+		t.Fatal("expected StartContext to return after cancel")
+	}
+
+	if len(w.RetrieveAllNodes()) != 8 {
+		t.Errorf("expected the file trees to be kept, got %d nodes", len(w.RetrieveAllNodes()))
+	}
+
+	// The gowatcher can be started again and still notices changes.
+	go func() {
+		errc <- w.Start(time.Millisecond * 10)
+	}()
+
+	newFile := filepath.Join(testDir, "newfile.txt")
+	if err := ioutil.WriteFile(newFile, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-w.Event:
+		if event.Op != Create || event.Path != newFile {
+			t.Errorf("expected a create event for %s, got %s", newFile, event)
+		}
+	case <-time.After(time.Millisecond * 250):
+		t.Fatal("received no event from Event channel")
+	}
+
+	w.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("expected error to be nil after Close, got %s", err)
+	}
+	select {
+	case <-w.Closed:
+	default:
+		t.Error("expected Closed to be closed")
+	}
+}
+
+func TestCloseNotRunning(t *testing.T) {
+	w := New()
+
+	done := make(chan struct{})
+	go func() {
+		w.Close()
+		w.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Millisecond * 250):
+		t.Fatal("expected Close to return when the gowatcher isn't running")
+	}
+}
+
+func TestAddPathWithInterval(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	fast := filepath.Join(testDir, "testDirTwo")
+	slow := filepath.Join(testDir, "testDirThree")
+	if err := os.Mkdir(slow, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.FilterOps(Create)
+	if err := w.AddPathWithInterval(fast, true, time.Millisecond*10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPathWithInterval(slow, true, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPathWithInterval(testDir, false, 0); err != ErrDurationTooShort {
+		t.Errorf("expected ErrDurationTooShort error, got %v", err)
+	}
+
+	go func() {
+		if err := w.Start(time.Hour); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer w.Close()
+	w.Wait()
+	// Let the first cycle, which polls every path, run before creating the files.
+	time.Sleep(time.Millisecond * 50)
+
+	for _, dir := range []string{slow, fast} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "newfile.txt"), []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case event := <-w.Event:
+		if event.Path != filepath.Join(fast, "newfile.txt") {
+			t.Errorf("expected a create event in %s, got %s", fast, event)
+		}
+	case <-time.After(time.Millisecond * 250):
+		t.Fatal("received no event from Event channel")
+	}
+
+	select {
+	case event := <-w.Event:
+		t.Errorf("expected no event before %s is due, got %s", slow, event)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestAddPathWithPriority(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	low := filepath.Join(testDir, "a")
+	high := filepath.Join(testDir, "b")
+	for _, dir := range []string{low, high} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New()
+	if err := w.AddPathWithPriority(low, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPathWithPriority(high, true, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{low, high} {
+		if err := os.Chmod(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := pollOnce(w)
+	if len(events) != 2 || events[0].Path != high || events[1].Path != low {
+		t.Errorf("expected events for %s then %s, got %v", high, low, events)
+	}
+
+	// The held Create events are sent by priority too.
+	for _, dir := range []string{low, high} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "x"), []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var created []string
+	for _, event := range pollOnce(w) {
+		if event.Op == Create {
+			created = append(created, event.Path)
+		}
+	}
+	if len(created) != 2 || created[0] != filepath.Join(high, "x") || created[1] != filepath.Join(low, "x") {
+		t.Errorf("expected create events in %s then %s, got %v", high, low, created)
+	}
+}
+
+func TestEventPayload(t *testing.T) {
+	fsys, testDir := setupMemFS(t)
+	other := filepath.Join(string(filepath.Separator), "other")
+	if err := fsys.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.SetFileSystem(fsys)
+	w.FilterOps(Write, Create)
+	for _, path := range []string{testDir, other} {
+		if err := w.AddPath(path, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	written := filepath.Join(testDir, "testDirTwo", "file_recursive.txt")
+	created := filepath.Join(other, "new.txt")
+	if err := fsys.WriteFile(written, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(created, []byte{}, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	done := make(chan struct{})
+	go func() {
+		w.cycle(context.Background(), func(evt chan Event, cancel chan struct{}) {
+			w.pollEvents(evt, cancel, nil)
+		})
+		close(done)
+	}()
+	events := make(map[string]Event)
+	var seq uint64
+collect:
+	for {
+		select {
+		case e := <-w.Event:
+			if e.Seq <= seq {
+				t.Errorf("expected the sequence number to increase, got %d after %d", e.Seq, seq)
+			}
+			seq = e.Seq
+			if e.Time.Before(before) {
+				t.Errorf("expected the detection time of %s", e)
+			}
+			events[e.Path] = e
+		case <-done:
+			break collect
+		}
+	}
+
+	e, found := events[written]
+	if !found || e.Op != Write {
+		t.Fatalf("expected a write event for %s, got %v", written, events)
+	}
+	if e.OldInfo == nil || e.OldInfo.Size() != 0 || e.Size() != int64(len("content")) {
+		t.Errorf("expected the old and new size of %s, got %v and %d", written, e.OldInfo, e.Size())
+	}
+	if e.Root != testDir || e.RelPath != filepath.Join("testDirTwo", "file_recursive.txt") {
+		t.Errorf("expected %s relative to %s, got %s relative to %s", written, testDir, e.RelPath, e.Root)
+	}
+	e, found = events[created]
+	if !found || e.Op != Create || e.Root != other || e.RelPath != "new.txt" {
+		t.Errorf("expected a create event for %s relative to %s, got %v", created, other, e)
+	}
+}
+
+func TestRetrieveAllNodesFromConsumer(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- w.StartContext(ctx, 10*time.Millisecond)
+	}()
+	w.Wait()
+
+	newFiles := map[string]bool{
+		filepath.Join(testDir, "new_1.txt"): true,
+		filepath.Join(testDir, "new_2.txt"): true,
+	}
+	for path := range newFiles {
+		if err := ioutil.WriteFile(path, []byte{}, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The trees aren't locked while the events are sent, so the consumer can read them.
+	timeout := time.After(time.Second)
+	for len(newFiles) > 0 {
+		select {
+		case event := <-w.Event:
+			if _, found := w.RetrieveAllNodes()[event.Path]; !found && event.Op == Create {
+				t.Errorf("expected to find %s", event.Path)
+			}
+			if err := w.SaveSnapshot(ioutil.Discard); err != nil {
+				t.Error(err)
+			}
+			delete(newFiles, event.Path)
+		case <-timeout:
+			t.Fatalf("expected events for %v", newFiles)
+		}
+	}
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected error to be %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestPairMovesReusedInode(t *testing.T) {
+	now := time.Now()
+	event := func(op Op, path string, size int64, modTime time.Time) Event {
+		return Event{Op: op, Path: path, FileInfo: &fileInfo{name: filepath.Base(path), size: size, modTime: modTime}}
+	}
+	// The filesystem reused the inode of the deleted file, but a rename would keep its ModTime and size.
+	sameInode := func(fi1, fi2 os.FileInfo) bool { return true }
+	events := pairMoves(
+		[]Event{event(Remove, filepath.Join("dir", "a"), 3, now)},
+		[]Event{event(Create, filepath.Join("dir", "b"), 5, now.Add(time.Second))},
+		sameInode,
+	)
+	if len(events) != 2 || events[0].Op != Remove || events[1].Op != Create {
+		t.Errorf("expected a remove and a create event, got %v", events)
+	}
+
+	events = pairMoves(
+		[]Event{event(Remove, filepath.Join("dir", "a"), 3, now)},
+		[]Event{event(Create, filepath.Join("dir", "b"), 3, now)},
+		sameInode,
+	)
+	if len(events) != 1 || events[0].Op != Rename || events[0].OldPath != filepath.Join("dir", "a") {
+		t.Errorf("expected a rename event, got %v", events)
+	}
+}
+
+func TestEventDeleteAndCreate(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	w := New()
+	if err := w.AddPath(testDir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new file may get the inode of the deleted one, it's still not a rename.
+	removed := filepath.Join(testDir, "file.txt")
+	created := filepath.Join(testDir, "other.txt")
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(created, []byte("content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ops := make(map[string]Op)
+	for _, event := range pollOnce(w) {
+		ops[event.Path] = event.Op
+	}
+	if op, found := ops[created]; ops[removed] != Remove || !found || op != Create {
+		t.Errorf("expected a remove event for %s and a create event for %s, got %v", removed, created, ops)
+	}
+}
+
+func BenchmarkPairMoves(b *testing.B) {
+	// A branch switch: every file is removed and created again with another content.
+	now := time.Now()
+	var removed, created []Event
+	for i := 0; i < 8000; i++ {
+		removed = append(removed, Event{Op: Remove, Path: filepath.Join("a", strconv.Itoa(i)),
+			FileInfo: &fileInfo{size: int64(i), modTime: now}})
+		created = append(created, Event{Op: Create, Path: filepath.Join("b", strconv.Itoa(i)),
+			FileInfo: &fileInfo{size: int64(i), modTime: now.Add(time.Second)}})
+	}
+	sameFile := func(fi1, fi2 os.FileInfo) bool { return false }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pairMoves(removed, created, sameFile)
+	}
+}
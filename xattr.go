@@ -0,0 +1,31 @@
+package gowatcher
+
+import "bytes"
+
+// xattrs returns the extended attributes of the file if they are part of the change policy.
+func (w *GoWatcher) xattrs(path string) map[string][]byte {
+	if w.changePolicy&ChangeXattr == 0 {
+		return nil
+	}
+	fsys, ok := w.fsys.(XattrFileSystem)
+	if !ok {
+		return nil
+	}
+	attrs, err := fsys.Xattrs(path)
+	if err != nil {
+		return nil
+	}
+	return attrs
+}
+
+func equalXattrs(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, found := b[k]; !found || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+// +build linux
+
+package gowatcher
+
+import (
+	"strings"
+	"syscall"
+)
+
+func readXattrs(path string) (map[string][]byte, error) {
+	attrs := make(map[string][]byte)
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil {
+		if err == syscall.ENOTSUP {
+			return attrs, nil
+		}
+		return nil, err
+	}
+	if size == 0 {
+		return attrs, nil
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name == "" {
+			continue
+		}
+		// An attribute can disappear between the two calls, just skip it.
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, size)
+		size, err = syscall.Getxattr(path, name, value)
+		if err != nil {
+			continue
+		}
+		attrs[name] = value[:size]
+	}
+	return attrs, nil
+}
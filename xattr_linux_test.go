@@ -0,0 +1,43 @@
+// +build linux
+
+package gowatcher
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestChangePolicyXattr(t *testing.T) {
+	testDir, teardown := setup(t)
+	defer teardown()
+
+	fileTxt := filepath.Join(testDir, "file.txt")
+	if err := syscall.Setxattr(fileTxt, "user.gowatcher", []byte("a"), 0); err != nil {
+		t.Skipf("extended attributes are not supported: %s", err)
+	}
+
+	w := New()
+	w.SetChangePolicy(DefaultChangePolicy | ChangeXattr)
+	if err := w.AddPath(testDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syscall.Setxattr(fileTxt, "user.gowatcher", []byte("b"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var xattrs []Event
+	for _, event := range pollOnce(w) {
+		if event.Op == Xattr {
+			xattrs = append(xattrs, event)
+		}
+	}
+	if len(xattrs) != 1 || xattrs[0].Path != fileTxt {
+		t.Fatalf("expected 1 xattr event for %s, got %v", fileTxt, xattrs)
+	}
+	if string(xattrs[0].OldXattrs["user.gowatcher"]) != "a" || string(xattrs[0].NewXattrs["user.gowatcher"]) != "b" {
+		t.Errorf("expected user.gowatcher to change from a to b, got %q to %q",
+			xattrs[0].OldXattrs["user.gowatcher"], xattrs[0].NewXattrs["user.gowatcher"])
+	}
+}